@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	lexicalImport = "github.com/tylertravisty/go-lexical"
+	nodesImport   = "github.com/tylertravisty/go-lexical/nodes"
+)
+
+// node is a struct annotated with a lexical type
+type node struct {
+	name     string
+	typeName string
+	kind     string
+	base     string
+	declared map[string]bool
+}
+
+// generator generates lexical node methods for the annotated structs of a package
+type generator struct {
+	pkg       string
+	nodesName string
+	nodes     []node
+	imports   map[string]bool
+	// pkgConst is whether the package declares the pkg constant prefixing its errors
+	pkgConst bool
+}
+
+// parseNodes finds the annotated structs in files. declared holds the methods already
+// declared for every type of the package, which are not generated.
+func parseNodes(files []*ast.File, declared map[string]map[string]bool) (*generator, error) {
+	g := &generator{imports: map[string]bool{}}
+	for _, file := range files {
+		g.pkg = file.Name.Name
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+
+				n, found, err := parseNode(file, ts.Name.Name, st)
+				if err != nil {
+					return nil, err
+				}
+
+				if !found {
+					continue
+				}
+
+				if n.base != "" && strings.Contains(n.base, ".") {
+					g.nodesName, _, _ = strings.Cut(n.base, ".")
+				}
+
+				n.declared = declared[n.name]
+				g.nodes = append(g.nodes, n)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+func parseNode(file *ast.File, name string, st *ast.StructType) (node, bool, error) {
+	for _, field := range st.Fields.List {
+		if len(field.Names) != 0 || field.Tag == nil {
+			continue
+		}
+
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return node{}, false, err
+		}
+
+		annotation, ok := reflect.StructTag(tag).Lookup("lexical")
+		if !ok {
+			continue
+		}
+
+		n := node{name: name, base: exprString(field.Type)}
+		for _, option := range strings.Split(annotation, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+			switch key {
+			case "type":
+				n.typeName = value
+			case "kind":
+				n.kind = value
+			default:
+				return node{}, false, fmt.Errorf("%s: unknown lexical option %q", name, key)
+			}
+		}
+
+		if n.typeName == "" {
+			return node{}, false, fmt.Errorf("%s: lexical annotation is missing type", name)
+		}
+
+		if n.kind == "" {
+			n.kind = baseKind(n.base)
+		}
+
+		switch n.kind {
+		case "element", "text", "decorator":
+		default:
+			return node{}, false, fmt.Errorf("%s: unknown lexical kind %q", name, n.kind)
+		}
+
+		if strings.Contains(n.base, ".") && !importsNodes(file, n.base) {
+			return node{}, false, fmt.Errorf("%s: base %s is not from %s", name, n.base, nodesImport)
+		}
+
+		return n, true, nil
+	}
+
+	return node{}, false, nil
+}
+
+// baseKind infers the kind of a node from its embedded base
+func baseKind(base string) string {
+	_, name, found := strings.Cut(base, ".")
+	if !found {
+		name = base
+	}
+
+	switch name {
+	case "TextNode":
+		return "text"
+	case "DecoratorNode":
+		return "decorator"
+	}
+
+	return "element"
+}
+
+func importsNodes(file *ast.File, base string) bool {
+	qualifier, _, _ := strings.Cut(base, ".")
+	for _, imp := range file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+
+		if name == qualifier {
+			return importPath == nodesImport
+		}
+	}
+
+	return false
+}
+
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return exprString(e.X)
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	}
+
+	return ""
+}
+
+// embedded returns the name of the embedded base field
+func (n node) embedded() string {
+	_, name, found := strings.Cut(n.base, ".")
+	if !found {
+		return n.base
+	}
+
+	return name
+}
+
+// generatedIdents are the identifiers the generated methods refer to, besides the receiver
+var generatedIdents = []string{
+	"_", "any", "byte", "child", "data", "dataB", "err", "error", "fmt", "found", "json",
+	"lexical", "nil", "node", "nodes", "pkg", "reflect", "string",
+}
+
+// receiver returns the receiver name of the generated methods of n: the shortest lower case
+// prefix of its name colliding with no identifier the generated methods refer to
+func (g *generator) receiver(n node) string {
+	used := map[string]bool{g.nodesName: true}
+	for _, ident := range generatedIdents {
+		used[ident] = true
+	}
+
+	for _, other := range g.nodes {
+		used[other.name] = true
+	}
+
+	name := strings.ToLower(n.name)
+	for i := range name {
+		r := name[:i+1]
+		if token.IsIdentifier(r) && !used[r] {
+			return r
+		}
+	}
+
+	return "recv"
+}
+
+func (n node) wants(method string) bool {
+	return !n.declared[method]
+}
+
+// generate returns the formatted source of the generated methods
+func (g *generator) generate(register string) ([]byte, error) {
+	var body bytes.Buffer
+	for _, n := range g.nodes {
+		g.generateNode(&body, n)
+	}
+
+	if register != "" && len(g.nodes) > 0 {
+		fmt.Fprintf(&body, "// %s registers the generated lexical node types\n", register)
+		fmt.Fprintf(&body, "func %s() error {\n\treturn lexical.RegisterNodes(\n", register)
+		for _, n := range g.nodes {
+			fmt.Fprintf(&body, "\t\t&%s{},\n", n.name)
+		}
+		fmt.Fprintf(&body, "\t)\n}\n")
+	}
+
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by lexicalgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", g.pkg)
+	for _, imp := range imports {
+		fmt.Fprintf(&src, "\t%q\n", imp)
+	}
+	fmt.Fprintf(&src, "\n\t%q\n", lexicalImport)
+	if g.nodesName != "" {
+		if g.nodesName == "nodes" {
+			fmt.Fprintf(&src, "\t%q\n", nodesImport)
+		} else {
+			fmt.Fprintf(&src, "\t%s %q\n", g.nodesName, nodesImport)
+		}
+	}
+	fmt.Fprintf(&src, ")\n\n")
+	src.Write(body.Bytes())
+
+	return format.Source(src.Bytes())
+}
+
+func (g *generator) generateNode(b *bytes.Buffer, n node) {
+	r := g.receiver(n)
+	find := "Find"
+	found := "nodes"
+	if g.nodesName != "" {
+		find = g.nodesName + ".Find"
+		found = "found"
+	}
+
+	fmt.Fprintf(b, "var _ lexical.Node = &%s{}\n\n", n.name)
+
+	if n.wants("Find") {
+		if n.kind == "element" {
+			fmt.Fprintf(b, "// Find saves %[1]s node to nodes if %[1]s type is in map and then calls find on children\n", n.typeName)
+		} else {
+			fmt.Fprintf(b, "// Find saves %[1]s node to nodes if %[1]s type is in map\n", n.typeName)
+		}
+		fmt.Fprintf(b, "func (%s *%s) Find(%s map[string][]lexical.Node) {\n", r, n.name, found)
+		fmt.Fprintf(b, "\t%s(%s, %s)\n", find, r, found)
+		if n.kind == "element" {
+			fmt.Fprintf(b, "\n\tfor _, child := range %s.Children {\n\t\tchild.Find(%s)\n\t}\n", r, found)
+		}
+		fmt.Fprintf(b, "}\n\n")
+	}
+
+	if n.wants("TextContentSize") {
+		fmt.Fprintf(b, "// TextContentSize returns the text content size of the %s node\n", n.typeName)
+		fmt.Fprintf(b, "func (%s *%s) TextContentSize() int {\n\treturn %s.%s.TextContentSize()\n}\n\n", r, n.name, r, n.embedded())
+	}
+
+	if n.wants("Type") {
+		g.imports["reflect"] = true
+		fmt.Fprintf(b, "// Type returns type of %s node\n", n.typeName)
+		fmt.Fprintf(b, "func (%s %s) Type() (string, reflect.Type) {\n\treturn %q, reflect.TypeOf(%s)\n}\n\n", r, n.name, n.typeName, r)
+	}
+
+	if n.wants("Unmarshal") {
+		g.imports["encoding/json"] = true
+		fmt.Fprintf(b, "// Unmarshal unmarshals the %s node\n", n.typeName)
+		fmt.Fprintf(b, "func (%s *%s) Unmarshal(data map[string]interface{}) error {\n", r, n.name)
+		fmt.Fprintf(b, "\tdataB, err := json.Marshal(data)\n\tif err != nil {\n\t\treturn err\n\t}\n\n")
+		fmt.Fprintf(b, "\treturn json.Unmarshal(dataB, %s)\n}\n\n", r)
+
+		if n.wants("JSONType") {
+			g.imports["reflect"] = true
+			fmt.Fprintf(b, "// JSONType returns the type of %s node, which is decoded from JSON directly\n", n.typeName)
+			fmt.Fprintf(b, "func (%s %s) JSONType() reflect.Type {\n\treturn reflect.TypeOf(%s)\n}\n\n", r, n.name, r)
+		}
+	}
+
+	if n.wants("Valid") {
+		g.imports["fmt"] = true
+		fmt.Fprintf(b, "// Valid verifies the %s node is valid\n", n.typeName)
+		fmt.Fprintf(b, "func (%s *%s) Valid() error {\n", r, n.name)
+		fmt.Fprintf(b, "\terr := %s.%s.Valid()\n\tif err != nil {\n", r, n.embedded())
+		if g.pkgConst {
+			fmt.Fprintf(b, "\t\treturn fmt.Errorf(\"%%s: invalid %s node: %%v\", pkg, err)\n", n.typeName)
+		} else {
+			fmt.Fprintf(b, "\t\treturn fmt.Errorf(\"%s: invalid %s node: %%v\", err)\n", g.pkg, n.typeName)
+		}
+		fmt.Fprintf(b, "\t}\n\n\treturn nil\n}\n\n")
+	}
+
+	if n.wants("MarshalJSON") {
+		g.imports["encoding/json"] = true
+		fmt.Fprintf(b, "// MarshalJSON marshals the %s node with its type\n", n.typeName)
+		fmt.Fprintf(b, "func (%s %s) MarshalJSON() ([]byte, error) {\n", r, n.name)
+		fmt.Fprintf(b, "\ttype node %s\n\t%s.NodeType = %q\n\tif %s.Version == 0 {\n\t\t%s.Version = 1\n\t}\n\n", n.name, r, n.typeName, r, r)
+		fmt.Fprintf(b, "\treturn json.Marshal(node(%s))\n}\n\n", r)
+	}
+}
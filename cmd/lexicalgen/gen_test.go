@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func parseSource(t *testing.T, src string) []*ast.File {
+	f, err := parser.ParseFile(token.NewFileSet(), "nodes.go", src, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal("parser.ParseFile err:", err)
+	}
+
+	return []*ast.File{f}
+}
+
+func TestGenerate(t *testing.T) {
+	src := `package custom
+
+import lexnodes "github.com/tylertravisty/go-lexical/nodes"
+
+type HeadingNode struct {
+	lexnodes.ElementNode ` + "`lexical:\"type=heading\"`" + `
+	Tag string ` + "`json:\"tag\"`" + `
+}
+
+type EquationNode struct {
+	lexnodes.DecoratorNode ` + "`lexical:\"type=equation\"`" + `
+}
+
+type plain struct {
+	lexnodes.ElementNode
+}
+`
+	files := parseSource(t, src)
+	declared := map[string]map[string]bool{}
+	addMethods(parseSource(t, "package custom\nfunc (en *EquationNode) Valid() error { return nil }\n")[0], declared)
+
+	g, err := parseNodes(files, declared)
+	if err != nil {
+		t.Fatal("parseNodes err:", err)
+	}
+
+	if len(g.nodes) != 2 {
+		t.Fatalf("expected 2 nodes; got %d", len(g.nodes))
+	}
+
+	out, err := g.generate("RegisterNodes")
+	if err != nil {
+		t.Fatal("generate err:", err)
+	}
+
+	generated := string(out)
+	expected := []string{
+		`lexnodes "github.com/tylertravisty/go-lexical/nodes"`,
+		"func (h *HeadingNode) Find(found map[string][]lexical.Node) {",
+		"for _, child := range h.Children {",
+		`return "heading", reflect.TypeOf(h)`,
+		"func (h *HeadingNode) Valid() error {",
+		"func (h HeadingNode) MarshalJSON() ([]byte, error) {",
+		"func (e *EquationNode) Unmarshal(data map[string]interface{}) error {",
+		"&HeadingNode{},",
+		"&EquationNode{},",
+	}
+	for _, e := range expected {
+		if !strings.Contains(generated, e) {
+			t.Fatalf("expected generated code to contain %q:\n%s", e, generated)
+		}
+	}
+
+	if strings.Contains(generated, "func (e *EquationNode) Valid() error") {
+		t.Fatal("expected declared EquationNode.Valid to be skipped")
+	}
+
+	if strings.Contains(generated, "range e.Children") {
+		t.Fatal("expected decorator Find without children")
+	}
+}
+
+func TestGenerateTypeChecks(t *testing.T) {
+	src := `package custom
+
+import n "github.com/tylertravisty/go-lexical/nodes"
+
+const pkg = "custom"
+
+type NoteNode struct {
+	n.ElementNode ` + "`lexical:\"type=note\"`" + `
+	Data string ` + "`json:\"data\"`" + `
+}
+
+type ErrNode struct {
+	n.TextNode ` + "`lexical:\"type=err\"`" + `
+}
+
+type f struct {
+	n.DecoratorNode ` + "`lexical:\"type=f\"`" + `
+}
+`
+	for _, pkgConst := range []bool{true, false} {
+		source := src
+		if !pkgConst {
+			source = strings.Replace(src, "const pkg", "const prefix", 1)
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "nodes.go", source, parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatal("parser.ParseFile err:", err)
+		}
+
+		g, err := parseNodes([]*ast.File{file}, nil)
+		if err != nil {
+			t.Fatal("parseNodes err:", err)
+		}
+		g.pkgConst = pkgConst
+
+		out, err := g.generate("RegisterNodes")
+		if err != nil {
+			t.Fatal("generate err:", err)
+		}
+
+		formatted, err := format.Source(out)
+		if err != nil || !bytes.Equal(formatted, out) {
+			t.Fatalf("expected formatted generated code; got err %v:\n%s", err, out)
+		}
+
+		generated, err := parser.ParseFile(fset, "nodes_lexical.go", out, 0)
+		if err != nil {
+			t.Fatal("parser.ParseFile err:", err)
+		}
+
+		conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+		_, err = conf.Check("custom", fset, []*ast.File{file, generated}, nil)
+		if err != nil {
+			t.Fatalf("type checking generated code err: %v\n%s", err, out)
+		}
+
+		prefix := `"custom: invalid note node: %v", err`
+		if pkgConst {
+			prefix = `"%s: invalid note node: %v", pkg, err`
+		}
+
+		if !strings.Contains(string(out), prefix) {
+			t.Fatalf("expected generated code to contain %q:\n%s", prefix, out)
+		}
+	}
+}
+
+func TestGenerateReturnsError(t *testing.T) {
+	tests := []string{
+		"package custom\nimport \"github.com/tylertravisty/go-lexical/nodes\"\ntype A struct {\n\tnodes.ElementNode `lexical:\"kind=element\"`\n}\n",
+		"package custom\nimport \"github.com/tylertravisty/go-lexical/nodes\"\ntype A struct {\n\tnodes.ElementNode `lexical:\"type=a,kind=block\"`\n}\n",
+		"package custom\nimport \"github.com/tylertravisty/go-lexical/nodes\"\ntype A struct {\n\tnodes.ElementNode `lexical:\"type=a,name=b\"`\n}\n",
+		"package custom\nimport nodes \"example.com/other\"\ntype A struct {\n\tnodes.ElementNode `lexical:\"type=a\"`\n}\n",
+	}
+
+	for _, test := range tests {
+		_, err := parseNodes(parseSource(t, test), nil)
+		if err == nil {
+			t.Fatal("parseNodes err is nil; expected non-nil err")
+		}
+	}
+}
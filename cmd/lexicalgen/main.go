@@ -0,0 +1,147 @@
+// Command lexicalgen generates the lexical.Node methods of custom node types.
+//
+// A node type is a struct embedding a base node from the nodes package, such as
+// nodes.ElementNode, nodes.TextNode or nodes.DecoratorNode, with a lexical
+// annotation on the embedded field:
+//
+//	//go:generate go run github.com/tylertravisty/go-lexical/cmd/lexicalgen
+//
+//	type HeadingNode struct {
+//		nodes.ElementNode `lexical:"type=heading"`
+//		Tag string `json:"tag"`
+//	}
+//
+// lexicalgen writes Find, TextContentSize, Type, Unmarshal, JSONType, Valid and
+// MarshalJSON methods for every annotated struct, skipping methods declared by hand,
+// and a function registering all generated node types. Element nodes find their
+// children. Errors of Valid are prefixed with the pkg constant of the package, or
+// with the package name when it declares none.
+// The kind of a node is inferred from its base and can be set with kind=element,
+// kind=text or kind=decorator. The base must not have a MarshalJSON method.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	output := flag.String("output", "", "output file; default <file>_lexical.go")
+	register := flag.String("register", "RegisterNodes", "name of the generated registration function; empty to skip")
+	flag.Parse()
+
+	err := run(flag.Args(), *output, *register)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lexicalgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(files []string, output, register string) error {
+	if len(files) == 0 {
+		gofile := os.Getenv("GOFILE")
+		if gofile == "" {
+			return fmt.Errorf("no input files")
+		}
+		files = []string{gofile}
+	}
+
+	if output == "" {
+		output = strings.TrimSuffix(files[0], ".go") + "_lexical.go"
+	}
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, f)
+	}
+
+	declared, consts, err := declarations(fset, filepath.Dir(files[0]), output)
+	if err != nil {
+		return err
+	}
+
+	g, err := parseNodes(parsed, declared)
+	if err != nil {
+		return err
+	}
+	g.pkgConst = consts["pkg"]
+
+	if len(g.nodes) == 0 {
+		return fmt.Errorf("no annotated node types in %s", strings.Join(files, ", "))
+	}
+
+	src, err := g.generate(register)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, src, 0o644)
+}
+
+// declarations returns the methods declared for each type in the package directory and the
+// constants of the package, ignoring tests and the output file
+func declarations(fset *token.FileSet, dir, output string) (map[string]map[string]bool, map[string]bool, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	declared := map[string]map[string]bool{}
+	consts := map[string]bool{}
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") || filepath.Clean(p) == filepath.Clean(output) {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		addMethods(f, declared)
+		addConsts(f, consts)
+	}
+
+	return declared, consts, nil
+}
+
+func addMethods(f *ast.File, declared map[string]map[string]bool) {
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
+			continue
+		}
+
+		recv := exprString(fn.Recv.List[0].Type)
+		if declared[recv] == nil {
+			declared[recv] = map[string]bool{}
+		}
+		declared[recv][fn.Name.Name] = true
+	}
+}
+
+func addConsts(f *ast.File, consts map[string]bool) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			for _, name := range spec.(*ast.ValueSpec).Names {
+				consts[name.Name] = true
+			}
+		}
+	}
+}
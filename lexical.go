@@ -1,48 +1,211 @@
 package lexical
 
 import (
-	"encoding/json"
 	"fmt"
+	"iter"
+	"maps"
 	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
 const (
 	pkg = "lexical"
 )
 
-// TypeMap holds the registered lexical node types
+// TypeMap holds the registered lexical node types. Once frozen, a type map cannot be
+// changed and its lookups do not lock.
 type TypeMap struct {
-	mu    sync.RWMutex
-	types map[string]reflect.Type
+	mu        sync.RWMutex
+	frozen    atomic.Bool
+	types     map[string]reflect.Type
+	factories map[string]func() Node
 }
 
-// Add associates a lexical node type with the given name
+func newTypeMap() *TypeMap {
+	return &TypeMap{
+		types:     map[string]reflect.Type{},
+		factories: map[string]func() Node{},
+	}
+}
+
+// Add associates a lexical node type with the given name. Add panics if the type map is frozen.
 func (tm *TypeMap) Add(name string, nodeType reflect.Type) {
+	tm.AddFunc(name, nodeType, nil)
+}
+
+// AddFunc associates a lexical node type with the given name, using factory to create its nodes.
+// AddFunc panics if the type map is frozen.
+func (tm *TypeMap) AddFunc(name string, nodeType reflect.Type, factory func() Node) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
+	if tm.frozen.Load() {
+		panic(fmt.Sprintf("%s: cannot add node type %s to frozen type map", pkg, name))
+	}
+
+	tm.set(name, nodeType, factory)
+}
+
+func (tm *TypeMap) set(name string, nodeType reflect.Type, factory func() Node) {
 	tm.types[name] = nodeType
+	if factory != nil {
+		tm.factories[name] = factory
+	} else {
+		delete(tm.factories, name)
+	}
 }
 
-// Type returns the lexical node type associated with given name
-func (tm *TypeMap) Type(name string) (reflect.Type, bool) {
+// Register adds the type of node unless its name is already registered
+func (tm *TypeMap) Register(node Node) error {
+	name, nodeType := node.Type()
+	return tm.register(name, nodeType, nil)
+}
+
+// RegisterFunc adds the type of the nodes created by factory unless its name is already registered
+func (tm *TypeMap) RegisterFunc(factory func() Node) error {
+	name, nodeType := factory().Type()
+	return tm.register(name, nodeType, factory)
+}
+
+func (tm *TypeMap) register(name string, nodeType reflect.Type, factory func() Node) error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
-	t, exists := tm.types[name]
+	if tm.frozen.Load() {
+		return fmt.Errorf("%s: node types are frozen", pkg)
+	}
+
+	if _, exists := tm.types[name]; exists {
+		return fmt.Errorf("%s: node type already exists: %v", pkg, name)
+	}
+
+	tm.set(name, nodeType, factory)
+	return nil
+}
+
+// Replace associates the registered name with the type of node, so nodes serialized with
+// that name are unmarshaled into the replacement
+func (tm *TypeMap) Replace(name string, node Node) error {
+	_, nodeType := node.Type()
+	return tm.replace(name, nodeType, nil)
+}
+
+// ReplaceFunc associates the registered name with the type of the nodes created by factory
+func (tm *TypeMap) ReplaceFunc(name string, factory func() Node) error {
+	_, nodeType := factory().Type()
+	return tm.replace(name, nodeType, factory)
+}
+
+func (tm *TypeMap) replace(name string, nodeType reflect.Type, factory func() Node) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.frozen.Load() {
+		return fmt.Errorf("%s: node types are frozen", pkg)
+	}
+
+	if _, exists := tm.types[name]; !exists {
+		return fmt.Errorf("%s: node type does not exist: %v", pkg, name)
+	}
+
+	tm.set(name, nodeType, factory)
+	return nil
+}
+
+// Unregister removes the lexical node type associated with the given name
+func (tm *TypeMap) Unregister(name string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.frozen.Load() {
+		return fmt.Errorf("%s: node types are frozen", pkg)
+	}
+
+	if _, exists := tm.types[name]; !exists {
+		return fmt.Errorf("%s: node type does not exist: %v", pkg, name)
+	}
+
+	delete(tm.types, name)
+	delete(tm.factories, name)
+	return nil
+}
+
+// Freeze prevents further changes to the type map
+func (tm *TypeMap) Freeze() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.frozen.Store(true)
+}
+
+// Frozen reports whether the type map is frozen
+func (tm *TypeMap) Frozen() bool {
+	return tm.frozen.Load()
+}
+
+// New returns a new node of the lexical node type associated with given name
+func (tm *TypeMap) New(name string) (Node, bool) {
+	nodeType, factory, exists := tm.lookup(name)
+	if !exists {
+		return nil, false
+	}
+
+	if factory != nil {
+		return factory(), true
+	}
+
+	node, ok := reflect.New(nodeType).Interface().(Node)
+	return node, ok
+}
+
+// Type returns the lexical node type associated with given name
+func (tm *TypeMap) Type(name string) (reflect.Type, bool) {
+	t, _, exists := tm.lookup(name)
 	return t, exists
 }
 
+func (tm *TypeMap) lookup(name string) (reflect.Type, func() Node, bool) {
+	if !tm.frozen.Load() {
+		tm.mu.RLock()
+		defer tm.mu.RUnlock()
+	}
+
+	t, exists := tm.types[name]
+	return t, tm.factories[name], exists
+}
+
+// Types returns an iterator over the registered names and node types, sorted by name
+func (tm *TypeMap) Types() iter.Seq2[string, reflect.Type] {
+	return func(yield func(string, reflect.Type) bool) {
+		locked := !tm.frozen.Load()
+		if locked {
+			tm.mu.RLock()
+		}
+		names := slices.Sorted(maps.Keys(tm.types))
+		types := maps.Clone(tm.types)
+		if locked {
+			tm.mu.RUnlock()
+		}
+
+		for _, name := range names {
+			if !yield(name, types[name]) {
+				return
+			}
+		}
+	}
+}
+
 // DefaultNodeTypes is a global type map for lexical nodes
-var DefaultNodeTypes = &TypeMap{types: map[string]reflect.Type{}}
+var DefaultNodeTypes = newTypeMap()
 
 // ResetNodes resets the default global node type map
 func ResetNodes() {
 	DefaultNodeTypes.mu.Lock()
 	defer DefaultNodeTypes.mu.Unlock()
 
-	DefaultNodeTypes = &TypeMap{types: map[string]reflect.Type{}}
+	DefaultNodeTypes = newTypeMap()
 }
 
 // Node defines the interface for lexical nodes
@@ -50,22 +213,47 @@ type Node interface {
 	Find(nodes map[string][]Node)
 	TextContentSize() int
 	Type() (string, reflect.Type)
+	// Unmarshal unmarshals the node from a decoded JSON object. UnmarshalNode calls it
+	// for every node that does not implement JSONNode.
 	Unmarshal(data map[string]interface{}) error
 	Valid() error
 }
 
+// JSONNode is implemented by nodes whose Unmarshal method only decodes the JSON encoding
+// of the object, so that UnmarshalNode decodes them from JSON directly without building
+// the object. JSONType returns the type declaring the method: a type embedding a JSONNode
+// is decoded by its own Unmarshal method unless it declares JSONType too.
+type JSONNode interface {
+	JSONType() reflect.Type
+}
+
 // NodeArray is an array of Nodes
 type NodeArray []Node
 
 // RegisterNode registers the lexical node
 func RegisterNode(node Node) error {
-	name, nodeType := node.Type()
-	if _, exists := DefaultNodeTypes.Type(name); exists {
-		return fmt.Errorf("%s: node type already exists: %v", pkg, name)
-	}
+	return DefaultNodeTypes.Register(node)
+}
 
-	DefaultNodeTypes.Add(name, nodeType)
-	return nil
+// RegisterNodeFunc registers a lexical node type whose nodes are created by factory
+func RegisterNodeFunc(factory func() Node) error {
+	return DefaultNodeTypes.RegisterFunc(factory)
+}
+
+// ReplaceNode replaces the registered node type of the given name with the type of node,
+// like the node replacement of the Lexical editor config
+func ReplaceNode(name string, node Node) error {
+	return DefaultNodeTypes.Replace(name, node)
+}
+
+// UnregisterNode unregisters the lexical node type of the given name
+func UnregisterNode(name string) error {
+	return DefaultNodeTypes.Unregister(name)
+}
+
+// FreezeNodes prevents further changes to the default global node type map
+func FreezeNodes() {
+	DefaultNodeTypes.Freeze()
 }
 
 // RegisterNodes registers the lexical nodes
@@ -87,41 +275,58 @@ func Unmarshal(data map[string]any) (Node, error) {
 		return nil, fmt.Errorf("%s: invalid node type", pkg)
 	}
 
-	nodeType, exists := DefaultNodeTypes.Type(nodeTypeName)
-	if !exists {
+	if _, exists := DefaultNodeTypes.Type(nodeTypeName); !exists {
 		return nil, fmt.Errorf("%s: unsupported node type", pkg)
 	}
 
-	node, ok := reflect.New(nodeType).Interface().(Node)
+	node, ok := DefaultNodeTypes.New(nodeTypeName)
 	if !ok {
 		return nil, fmt.Errorf("%s: invalid node", pkg)
 	}
 
-	err := node.Unmarshal(data)
+	data, err := migrateMap(nodeTypeName, node, data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = node.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: error unmarshaling node: %v", pkg, err)
+	}
+
+	return node, nil
+}
+
+// UnmarshalNode unmarshals a serialized node into a node of its registered type.
+// Nodes implementing JSONNode are decoded directly from data with encoding/json, without
+// an intermediate map. Other nodes are decoded by their Unmarshal method.
+func UnmarshalNode(data []byte) (Node, error) {
+	node, end, err := decodeNode(data, 0)
 	if err != nil {
 		return nil, fmt.Errorf("%s: error unmarshaling node: %v", pkg, err)
 	}
 
+	if skipSpace(data, end) != len(data) {
+		return nil, fmt.Errorf("%s: error unmarshaling node: unexpected data after node", pkg)
+	}
+
 	return node, nil
 }
 
 // UnmarshalJSON unmarshals bytes into node array
 func (na *NodeArray) UnmarshalJSON(data []byte) error {
-	var obj []map[string]interface{}
-	err := json.Unmarshal(data, &obj)
+	if string(data) == "null" {
+		*na = nil
+		return nil
+	}
+
+	array, end, err := decodeNodes(data, 0)
 	if err != nil {
 		return err
 	}
 
-	var array []Node
-	var node Node
-	for _, data := range obj {
-		node, err = Unmarshal(data)
-		if err != nil {
-			return err
-		}
-
-		array = append(array, node)
+	if skipSpace(data, end) != len(data) {
+		return fmt.Errorf("%s: unexpected data after node array", pkg)
 	}
 
 	*na = array
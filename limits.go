@@ -0,0 +1,146 @@
+package lexical
+
+import "fmt"
+
+// Limits bounds the documents accepted by CheckLimits. Zero fields are not enforced.
+type Limits struct {
+	// MaxBytes is the maximum size of the serialized document
+	MaxBytes int
+	// MaxDepth is the maximum nesting depth of children arrays
+	MaxDepth int
+	// MaxNodes is the maximum number of nodes
+	MaxNodes int
+	// MaxChildren is the maximum number of children of a single node
+	MaxChildren int
+	// MaxTextLength is the maximum length in bytes of a serialized text field
+	MaxTextLength int
+}
+
+// DefaultLimits are limits suitable for documents written by people
+var DefaultLimits = Limits{
+	MaxBytes:      8 << 20,
+	MaxDepth:      64,
+	MaxNodes:      100000,
+	MaxChildren:   10000,
+	MaxTextLength: 1 << 20,
+}
+
+// LimitError is returned when a document exceeds one of its limits
+type LimitError struct {
+	// Limit is the name of the exceeded limit field
+	Limit string
+	Max   int
+	// Offset is the byte offset in the document where the limit was exceeded
+	Offset int
+}
+
+func (le *LimitError) Error() string {
+	return fmt.Sprintf("%s: document exceeds %s of %d at offset %d", pkg, le.Limit, le.Max, le.Offset)
+}
+
+type scanFrame struct {
+	object   bool
+	children bool
+	count    int
+}
+
+// CheckLimits scans a serialized document and returns a *LimitError if it exceeds limits.
+// The scan does not build the document and does not recurse, so hostile documents
+// are rejected before any node is allocated.
+func CheckLimits(data []byte, limits Limits) error {
+	return CheckNodeArrayLimits(data, limits)
+}
+
+// CheckNodeArrayLimits is CheckLimits for serialized values whose top-level object holds
+// nodes in the arrays of keys, such as the "nodes" array of clipboard data. Those arrays
+// count toward limits as children arrays do.
+func CheckNodeArrayLimits(data []byte, limits Limits, keys ...string) error {
+	if limits.MaxBytes > 0 && len(data) > limits.MaxBytes {
+		return &LimitError{Limit: "MaxBytes", Max: limits.MaxBytes, Offset: limits.MaxBytes}
+	}
+
+	var stack []scanFrame
+	var key []byte
+	depth, nodes := 0, 0
+	expectKey := false
+	for i := skipSpace(data, 0); i < len(data); i = skipSpace(data, i) {
+		switch c := data[i]; c {
+		case '{', '[':
+			frame := scanFrame{object: c == '{'}
+			if n := len(stack); n > 0 && stack[n-1].children {
+				stack[n-1].count++
+				if limits.MaxChildren > 0 && stack[n-1].count > limits.MaxChildren {
+					return &LimitError{Limit: "MaxChildren", Max: limits.MaxChildren, Offset: i}
+				}
+
+				nodes++
+				if limits.MaxNodes > 0 && nodes > limits.MaxNodes {
+					return &LimitError{Limit: "MaxNodes", Max: limits.MaxNodes, Offset: i}
+				}
+			}
+
+			if n := len(stack); c == '[' && n > 0 && stack[n-1].object && (keyEquals(key, "children") || n == 1 && keyIn(key, keys)) {
+				frame.children = true
+				depth++
+				if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+					return &LimitError{Limit: "MaxDepth", Max: limits.MaxDepth, Offset: i}
+				}
+			}
+
+			stack = append(stack, frame)
+			expectKey = frame.object
+			i++
+		case '}', ']':
+			if len(stack) == 0 {
+				return fmt.Errorf("%s: invalid document at offset %d", pkg, i)
+			}
+
+			if stack[len(stack)-1].children {
+				depth--
+			}
+
+			stack = stack[:len(stack)-1]
+			expectKey = false
+			i++
+		case ',':
+			expectKey = len(stack) > 0 && stack[len(stack)-1].object
+			i++
+		case ':':
+			i++
+		case '"':
+			end := skipString(data, i)
+			if end < 0 {
+				return fmt.Errorf("%s: invalid string at offset %d", pkg, i)
+			}
+
+			if expectKey {
+				key = data[i:end]
+				expectKey = false
+			} else if limits.MaxTextLength > 0 && keyEquals(key, "text") && end-i-2 > limits.MaxTextLength {
+				return &LimitError{Limit: "MaxTextLength", Max: limits.MaxTextLength, Offset: i}
+			}
+
+			i = end
+		default:
+			end := skipValue(data, i)
+			if end < 0 {
+				return fmt.Errorf("%s: invalid value at offset %d", pkg, i)
+			}
+
+			i = end
+		}
+	}
+
+	return nil
+}
+
+// keyIn reports whether the serialized key equals one of keys
+func keyIn(key []byte, keys []string) bool {
+	for _, k := range keys {
+		if keyEquals(key, k) {
+			return true
+		}
+	}
+
+	return false
+}
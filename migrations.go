@@ -0,0 +1,290 @@
+package lexical
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Migration upgrades the serialized form of a node from one version to the next.
+// data holds every serialized property of the node, including its children.
+type Migration func(data map[string]any) error
+
+// Versioned is implemented by nodes that declare the current version of their serialized form
+type Versioned interface {
+	CurrentVersion() int
+}
+
+// MigrationMap holds the registered node migrations
+type MigrationMap struct {
+	mu          sync.RWMutex
+	migrations  map[string]map[int]Migration
+	upgrades    map[string]map[string]Migration
+	rejectNewer bool
+}
+
+func newMigrationMap() *MigrationMap {
+	return &MigrationMap{
+		migrations: map[string]map[int]Migration{},
+		upgrades:   map[string]map[string]Migration{},
+	}
+}
+
+// Add associates a migration from the given version with the named node type
+func (mm *MigrationMap) Add(name string, from int, migration Migration) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if mm.migrations[name] == nil {
+		mm.migrations[name] = map[int]Migration{}
+	}
+	mm.migrations[name][from] = migration
+}
+
+// AddUpgrade associates with the named node type an upgrade of serialized nodes missing field.
+// Upgrades bring nodes to the current shape when Lexical added a field without changing the
+// node version, and run after the version migrations.
+func (mm *MigrationMap) AddUpgrade(name, field string, upgrade Migration) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if mm.upgrades[name] == nil {
+		mm.upgrades[name] = map[string]Migration{}
+	}
+	mm.upgrades[name][field] = upgrade
+}
+
+// Upgrade returns the upgrade of the named node type for nodes missing field
+func (mm *MigrationMap) Upgrade(name, field string) (Migration, bool) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	u, exists := mm.upgrades[name][field]
+	return u, exists
+}
+
+// upgradeFields returns the fields whose absence upgrades nodes of the named type, sorted
+func (mm *MigrationMap) upgradeFields(name string) []string {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(mm.upgrades[name]))
+}
+
+// Migration returns the migration from the given version of the named node type
+func (mm *MigrationMap) Migration(name string, from int) (Migration, bool) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	m, exists := mm.migrations[name][from]
+	return m, exists
+}
+
+// RejectNewer sets whether nodes with a version newer than their current version are rejected
+func (mm *MigrationMap) RejectNewer(reject bool) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.rejectNewer = reject
+}
+
+func (mm *MigrationMap) rejectsNewer() bool {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	return mm.rejectNewer
+}
+
+// DefaultMigrations is a global migration map for lexical nodes
+var DefaultMigrations = newMigrationMap()
+
+// ResetMigrations removes every migration and upgrade from the default global migration map
+func ResetMigrations() {
+	DefaultMigrations.mu.Lock()
+	defer DefaultMigrations.mu.Unlock()
+
+	DefaultMigrations.migrations = map[string]map[int]Migration{}
+	DefaultMigrations.upgrades = map[string]map[string]Migration{}
+	DefaultMigrations.rejectNewer = false
+}
+
+// RegisterMigration registers a migration of the named node type from the given version to the next
+func RegisterMigration(name string, from int, migration Migration) error {
+	return DefaultMigrations.register(name, from, migration)
+}
+
+// RegisterUpgrade registers an upgrade of serialized nodes of the named type missing field
+func RegisterUpgrade(name, field string, upgrade Migration) error {
+	return DefaultMigrations.registerUpgrade(name, field, upgrade)
+}
+
+func (mm *MigrationMap) register(name string, from int, migration Migration) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if _, exists := mm.migrations[name][from]; exists {
+		return fmt.Errorf("%s: migration already exists: %v from version %d", pkg, name, from)
+	}
+
+	if mm.migrations[name] == nil {
+		mm.migrations[name] = map[int]Migration{}
+	}
+	mm.migrations[name][from] = migration
+	return nil
+}
+
+func (mm *MigrationMap) registerUpgrade(name, field string, upgrade Migration) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if _, exists := mm.upgrades[name][field]; exists {
+		return fmt.Errorf("%s: upgrade already exists: %v missing %s", pkg, name, field)
+	}
+
+	if mm.upgrades[name] == nil {
+		mm.upgrades[name] = map[string]Migration{}
+	}
+	mm.upgrades[name][field] = upgrade
+	return nil
+}
+
+// needsMigration reports whether a serialized node of the given version, whose fields are
+// reported by has, must be migrated or upgraded. Both decode paths decide with it, so a node
+// is accepted or rejected the same way whichever path decodes it. Nodes without a version
+// are assumed to be current.
+func needsMigration(name string, node Node, version int, has func(field string) bool) (bool, error) {
+	migrateNode := false
+	if versioned, ok := node.(Versioned); ok && version != 0 {
+		current := versioned.CurrentVersion()
+		if version > current && DefaultMigrations.rejectsNewer() {
+			return false, fmt.Errorf("%s: unsupported %s node version %d; current version is %d", pkg, name, version, current)
+		}
+
+		migrateNode = version < current
+	}
+
+	return migrateNode || needsUpgrade(name, has), nil
+}
+
+// migrate upgrades the serialized node data to the node's current version and shape
+func migrate(name string, node Node, data map[string]any, version int) error {
+	current := version
+	if versioned, ok := node.(Versioned); ok && version > 0 {
+		current = max(version, versioned.CurrentVersion())
+	}
+
+	for v := version; v < current; v++ {
+		m, exists := DefaultMigrations.Migration(name, v)
+		if !exists {
+			return fmt.Errorf("%s: no migration for %s node from version %d", pkg, name, v)
+		}
+
+		err := m(data)
+		if err != nil {
+			return fmt.Errorf("%s: error migrating %s node from version %d: %v", pkg, name, v, err)
+		}
+
+		data["version"] = v + 1
+	}
+
+	for _, field := range DefaultMigrations.upgradeFields(name) {
+		if _, exists := data[field]; exists {
+			continue
+		}
+
+		upgrade, _ := DefaultMigrations.Upgrade(name, field)
+		err := upgrade(data)
+		if err != nil {
+			return fmt.Errorf("%s: error upgrading %s node missing %s: %v", pkg, name, field, err)
+		}
+	}
+
+	return nil
+}
+
+// needsUpgrade reports whether the serialized node misses a field of an upgrade of its type.
+// A field is missing when has reports false.
+func needsUpgrade(name string, has func(field string) bool) bool {
+	for _, field := range DefaultMigrations.upgradeFields(name) {
+		if !has(field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// needsMigrationJSON reports whether the serialized node must be migrated or upgraded.
+// data may omit the children of the node.
+func needsMigrationJSON(name string, node Node, data []byte) (bool, error) {
+	rawVersion, exists, err := peekField(data, "version")
+	if err != nil {
+		return false, err
+	}
+
+	var version any
+	if exists {
+		json.Unmarshal(rawVersion, &version)
+	}
+
+	return needsMigration(name, node, versionOf(version), func(field string) bool {
+		_, exists, _ := peekField(data, field)
+		return exists
+	})
+}
+
+// versionOf returns the version held by the decoded version field of a serialized node,
+// or 0 when it holds no version
+func versionOf(version any) int {
+	switch v := version.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+
+	return 0
+}
+
+// migrateJSON upgrades the serialized node to the node's current version and shape
+func migrateJSON(name string, node Node, data []byte) ([]byte, error) {
+	var obj map[string]any
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrate(name, node, obj, versionOf(obj["version"]))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(obj)
+}
+
+// migrateMap upgrades the serialized node to the node's current version if needed,
+// returning a copy of data when the node is migrated
+func migrateMap(name string, node Node, data map[string]any) (map[string]any, error) {
+	version := versionOf(data["version"])
+	migrateNode, err := needsMigration(name, node, version, func(field string) bool {
+		_, exists := data[field]
+		return exists
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !migrateNode {
+		return data, nil
+	}
+
+	data = maps.Clone(data)
+	err = migrate(name, node, data, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
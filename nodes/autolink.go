@@ -29,6 +29,11 @@ func (aln AutoLinkNode) Type() (string, reflect.Type) {
 	return "autolink", reflect.TypeOf(aln)
 }
 
+// JSONType returns the type of autolink node, which is decoded from JSON directly
+func (aln AutoLinkNode) JSONType() reflect.Type {
+	return reflect.TypeOf(aln)
+}
+
 // Unmarshal unmarshals the autolink node
 func (aln *AutoLinkNode) Unmarshal(data map[string]interface{}) error {
 	alnB, err := json.Marshal(data)
@@ -38,3 +43,16 @@ func (aln *AutoLinkNode) Unmarshal(data map[string]interface{}) error {
 
 	return json.Unmarshal(alnB, aln)
 }
+
+// NewAutoLinkNode returns an autolink node to url wrapping children
+func NewAutoLinkNode(url string, children ...lexical.Node) *AutoLinkNode {
+	return &AutoLinkNode{
+		LinkNode: LinkNode{
+			ElementNode: ElementNode{
+				BaseNode: BaseNode{NodeType: "autolink", Version: 1},
+				Children: children,
+			},
+			URL: url,
+		},
+	}
+}
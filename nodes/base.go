@@ -17,3 +17,8 @@ func (bn *BaseNode) Unmarshal(data map[string]any) error {
 
 	return json.Unmarshal(bnB, bn)
 }
+
+// CurrentVersion returns the current version of the serialized node
+func (bn *BaseNode) CurrentVersion() int {
+	return 1
+}
@@ -0,0 +1,229 @@
+package nodes
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// ChunkOptions are the options of ChunkDocument
+type ChunkOptions struct {
+	// MaxSize is the maximum size of a chunk
+	MaxSize int
+	// Overlap is the size of the text at the end of a chunk repeated at the start of the next
+	Overlap int
+	// Unit counts sizes and offsets
+	Unit Unit
+}
+
+// Chunk is a piece of the text content of a document
+type Chunk struct {
+	Text string
+	// Start and End are the offsets of the chunk in the text content of the document
+	Start int
+	End   int
+	// Sources are the parts of the blocks the chunk was taken from, in document order
+	Sources []ChunkSource
+}
+
+// ChunkSource is the part of a block a chunk was taken from
+type ChunkSource struct {
+	// Block is the path of the block
+	Block Path
+	Start Position
+	End   Position
+}
+
+// ChunkDocument splits the text content of root into chunks of at most MaxSize. Chunks end at
+// block boundaries when they can, otherwise at sentence ends, then at word ends. Each chunk
+// after the first starts with up to Overlap of the text before it, from the start of a word.
+func ChunkDocument(root *RootNode, opts ChunkOptions) ([]Chunk, error) {
+	if opts.MaxSize <= 0 {
+		return nil, fmt.Errorf("%s: invalid chunk size %d", pkg, opts.MaxSize)
+	}
+
+	if opts.Overlap < 0 || opts.Overlap >= opts.MaxSize {
+		return nil, fmt.Errorf("%s: invalid chunk overlap %d", pkg, opts.Overlap)
+	}
+
+	c := newChunker(root, opts)
+	var pieces []textSpan
+	for _, block := range c.blocks {
+		pieces = append(pieces, c.split(block.span, splitSentences)...)
+	}
+
+	var chunks []Chunk
+	start, end := -1, -1
+	for _, piece := range pieces {
+		if start >= 0 && piece.end-start <= opts.MaxSize {
+			end = piece.end
+			continue
+		}
+
+		if start >= 0 {
+			chunks = append(chunks, c.chunk(start, end))
+		}
+
+		start, end = c.overlapStart(piece, end), piece.end
+	}
+
+	if start >= 0 {
+		chunks = append(chunks, c.chunk(start, end))
+	}
+
+	return chunks, nil
+}
+
+type chunkBlock struct {
+	path Path
+	span textSpan
+}
+
+type chunker struct {
+	opts   ChunkOptions
+	index  *TextIndex
+	text   string
+	bytes  []int
+	blocks []chunkBlock
+}
+
+func newChunker(root *RootNode, opts ChunkOptions) *chunker {
+	c := &chunker{opts: opts, index: NewTextIndex(root, opts.Unit)}
+	c.text = c.index.Text()
+
+	c.bytes = make([]int, 0, c.index.Len()+1)
+	for i, r := range c.text {
+		c.bytes = append(c.bytes, i)
+		if opts.Unit == UTF16 && r >= 0x10000 {
+			c.bytes = append(c.bytes, -1)
+		}
+	}
+	c.bytes = append(c.bytes, len(c.text))
+
+	Walk(root, func(node lexical.Node, path Path) bool {
+		if len(path) == 0 || !blockElement(node) {
+			return true
+		}
+
+		for _, child := range *node.(Parent).ChildNodes() {
+			if blockElement(child) {
+				return true
+			}
+		}
+
+		span := c.index.spans[path.key()]
+		if span.end > span.start {
+			c.blocks = append(c.blocks, chunkBlock{path: path, span: span})
+		}
+
+		return false
+	})
+
+	return c
+}
+
+type splitLevel int
+
+const (
+	splitSentences splitLevel = iota
+	splitWords
+	splitChars
+)
+
+// split splits span into pieces of at most MaxSize, at the given level first
+func (c *chunker) split(span textSpan, level splitLevel) []textSpan {
+	if span.end-span.start <= c.opts.MaxSize {
+		return []textSpan{span}
+	}
+
+	var parts []textSpan
+	switch level {
+	case splitSentences:
+		parts = c.segments(span, func(r, next rune) bool {
+			return (r == '.' || r == '!' || r == '?') && unicode.IsSpace(next)
+		})
+	case splitWords:
+		parts = c.segments(span, func(r, next rune) bool {
+			return !unicode.IsSpace(r) && unicode.IsSpace(next)
+		})
+	default:
+		for start := span.start; start < span.end; {
+			end := min(start+c.opts.MaxSize, span.end)
+			for end > start+1 && c.bytes[end] < 0 {
+				end--
+			}
+			parts = append(parts, textSpan{start: start, end: end})
+			start = end
+		}
+		return parts
+	}
+
+	var pieces []textSpan
+	for _, part := range parts {
+		pieces = append(pieces, c.split(part, level+1)...)
+	}
+
+	return pieces
+}
+
+// segments splits span after every rune for which end reports true given the next rune.
+// Whitespace between segments is not part of any segment.
+func (c *chunker) segments(span textSpan, end func(r, next rune) bool) []textSpan {
+	var segments []textSpan
+	start := -1
+	offset := span.start
+	for i := c.bytes[span.start]; i < c.bytes[span.end]; {
+		r, size := utf8.DecodeRuneInString(c.text[i:])
+		next, _ := utf8.DecodeRuneInString(c.text[i+size : c.bytes[span.end]])
+		if start < 0 && !unicode.IsSpace(r) {
+			start = offset
+		}
+
+		i += size
+		offset += textLength(string(r), c.opts.Unit)
+		if start >= 0 && (end(r, next) || i == c.bytes[span.end]) {
+			segments = append(segments, textSpan{start: start, end: offset})
+			start = -1
+		}
+	}
+
+	return segments
+}
+
+// overlapStart returns the start of the chunk beginning with piece after a chunk ending at prevEnd
+func (c *chunker) overlapStart(piece textSpan, prevEnd int) int {
+	if prevEnd < 0 || c.opts.Overlap == 0 {
+		return piece.start
+	}
+
+	start := max(prevEnd-c.opts.Overlap, piece.end-c.opts.MaxSize, 0)
+	for start < piece.start && (c.bytes[start] < 0 || start > 0 && !c.wordStart(start)) {
+		start++
+	}
+
+	return start
+}
+
+func (c *chunker) wordStart(offset int) bool {
+	r, _ := utf8.DecodeRuneInString(c.text[c.bytes[offset]:])
+	prev, _ := utf8.DecodeLastRuneInString(c.text[:c.bytes[offset]])
+	return !unicode.IsSpace(r) && unicode.IsSpace(prev)
+}
+
+func (c *chunker) chunk(start, end int) Chunk {
+	chunk := Chunk{Text: c.text[c.bytes[start]:c.bytes[end]], Start: start, End: end}
+	for _, block := range c.blocks {
+		if block.span.end <= start || block.span.start >= end {
+			continue
+		}
+
+		source := ChunkSource{Block: block.path}
+		source.Start, _ = c.index.Position(max(start, block.span.start))
+		source.End, _ = c.index.Position(min(end, block.span.end))
+		chunk.Sources = append(chunk.Sources, source)
+	}
+
+	return chunk
+}
@@ -0,0 +1,75 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// ClipboardMIMEType is the MIME type of the clipboard payload of the Lexical editor
+const ClipboardMIMEType = "application/x-lexical-editor"
+
+// Clipboard is the clipboard payload of the Lexical editor
+type Clipboard struct {
+	// Namespace is the namespace of the editor the nodes were copied from
+	Namespace string            `json:"namespace"`
+	Nodes     lexical.NodeArray `json:"nodes"`
+}
+
+// NewClipboard returns a clipboard payload holding copies of nodes
+func NewClipboard(namespace string, nodes lexical.NodeArray) *Clipboard {
+	return &Clipboard{Namespace: namespace, Nodes: cloneNodes(nodes)}
+}
+
+// CopyRange returns the clipboard payload of the range of root between start and end, with
+// text offsets counted in UTF-16 code units. As in Lexical, a range inside a single paragraph
+// copies its inline nodes without the paragraph.
+func CopyRange(root *RootNode, start, end Position, namespace string) (*Clipboard, error) {
+	extracted, err := Extract(root, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := extracted.Root.Children
+	if len(nodes) == 1 && inlineContainer(nodes[0]) && len(start.Path) > 0 && len(end.Path) > 0 {
+		nodes = *nodes[0].(Parent).ChildNodes()
+	}
+
+	return &Clipboard{Namespace: namespace, Nodes: nodes}, nil
+}
+
+// ParseClipboard parses a clipboard payload. Payloads exceeding limits are rejected with a
+// *lexical.LimitError before they are unmarshaled, counting the clipboard nodes as the
+// children of a root.
+func ParseClipboard(data []byte, limits lexical.Limits) (*Clipboard, error) {
+	err := lexical.CheckNodeArrayLimits(data, limits, "nodes")
+	if err != nil {
+		return nil, err
+	}
+
+	var clipboard Clipboard
+	err = json.Unmarshal(data, &clipboard)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid clipboard: %v", pkg, err)
+	}
+
+	return &clipboard, nil
+}
+
+// Document returns a document holding copies of the clipboard nodes, repaired so that inline
+// nodes are wrapped in paragraphs
+func (c *Clipboard) Document() *RootNode {
+	root := &RootNode{Root: ElementNode{
+		BaseNode: BaseNode{NodeType: "root", Version: 1},
+		Children: cloneNodes(c.Nodes),
+	}}
+	Repair(root)
+	return root
+}
+
+// InsertClipboard pastes the clipboard nodes into target at pos like InsertDocument, so inline
+// nodes pasted inside a paragraph join its content
+func InsertClipboard(target *RootNode, pos Position, clipboard *Clipboard) error {
+	return InsertDocument(target, pos, clipboard.Document())
+}
@@ -0,0 +1,230 @@
+package nodes
+
+import (
+	"reflect"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// Cloner is implemented by nodes that provide their own deep copy.
+// Clone must return a copy that shares no mutable state with the original.
+type Cloner interface {
+	Clone() lexical.Node
+}
+
+// Clone returns a deep copy of the node
+//
+// Nodes implementing Cloner are copied with their own Clone method, all other
+// nodes are copied field by field. Unexported fields are copied shallowly.
+func Clone(node lexical.Node) lexical.Node {
+	if node == nil {
+		return nil
+	}
+
+	if cloner, ok := node.(Cloner); ok {
+		return cloner.Clone()
+	}
+
+	clone, ok := cloneValue(reflect.ValueOf(node)).Interface().(lexical.Node)
+	if !ok {
+		return nil
+	}
+
+	return clone
+}
+
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+
+		var elem reflect.Value
+		if node, ok := v.Elem().Interface().(lexical.Node); ok {
+			elem = reflect.ValueOf(Clone(node))
+		} else {
+			elem = cloneValue(v.Elem())
+		}
+
+		c := reflect.New(v.Type()).Elem()
+		c.Set(elem)
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), cloneValue(iter.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cloneValue(v.Field(i)))
+			}
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// EqualOption configures the comparison made by Equal
+type EqualOption func(*equalConfig)
+
+type equalField struct {
+	owner reflect.Type
+	name  string
+}
+
+type equalConfig struct {
+	ignore map[equalField]bool
+}
+
+func (ec *equalConfig) skip(owner reflect.Type, names ...string) {
+	for _, name := range names {
+		ec.ignore[equalField{owner: owner, name: name}] = true
+	}
+}
+
+// IgnoreVersion ignores differences in node versions
+func IgnoreVersion() EqualOption {
+	return func(ec *equalConfig) {
+		ec.skip(reflect.TypeOf(BaseNode{}), "Version")
+	}
+}
+
+// IgnoreDirection ignores differences in element directions
+func IgnoreDirection() EqualOption {
+	return func(ec *equalConfig) {
+		ec.skip(reflect.TypeOf(ElementNode{}), "Direction")
+	}
+}
+
+// IgnoreIndent ignores differences in element indents
+func IgnoreIndent() EqualOption {
+	return func(ec *equalConfig) {
+		ec.skip(reflect.TypeOf(ElementNode{}), "Indent")
+	}
+}
+
+// IgnoreFormat ignores differences in element formats, text formats and paragraph text formats
+func IgnoreFormat() EqualOption {
+	return func(ec *equalConfig) {
+		ec.skip(reflect.TypeOf(ElementNode{}), "Format")
+		ec.skip(reflect.TypeOf(TextNode{}), "Format")
+		ec.skip(reflect.TypeOf(ParagraphNode{}), "TextFormat", "TextStyle")
+	}
+}
+
+// Equal reports whether two nodes are structurally equal
+//
+// Nodes are equal when they have the same concrete type and all of their
+// fields, including children, are equal. Nil and empty slices are equal.
+func Equal(a, b lexical.Node, opts ...EqualOption) bool {
+	config := &equalConfig{ignore: map[equalField]bool{}}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return equalValues(reflect.ValueOf(a), reflect.ValueOf(b), config)
+}
+
+func equalValues(a, b reflect.Value, config *equalConfig) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+
+		return equalValues(a.Elem(), b.Elem(), config)
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i), config) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		iter := a.MapRange()
+		for iter.Next() {
+			bv := b.MapIndex(iter.Key())
+			if !bv.IsValid() || !equalValues(iter.Value(), bv, config) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < a.NumField(); i++ {
+			if config.ignore[equalField{owner: t, name: t.Field(i).Name}] {
+				continue
+			}
+
+			if !equalValues(a.Field(i), b.Field(i), config) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	default:
+		return a.IsNil() && b.IsNil()
+	}
+}
@@ -0,0 +1,140 @@
+package nodes
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// ChildKind is the kind of children a node may have
+type ChildKind int
+
+const (
+	// ChildrenNone allows no children
+	ChildrenNone ChildKind = iota
+	// ChildrenInline allows inline children
+	ChildrenInline
+	// ChildrenBlock allows block children
+	ChildrenBlock
+	// ChildrenAny allows inline and block children
+	ChildrenAny
+)
+
+// ContentRule describes where nodes of a type may appear in a document
+type ContentRule struct {
+	// Inline reports whether the node may appear among inline content
+	Inline bool
+	// Block reports whether the node may appear among blocks
+	Block bool
+	// Children is the kind of children the node may have
+	Children ChildKind
+	// AllowedChildren restricts the types of the children of the node; empty allows any type of the right kind
+	AllowedChildren []string
+	// AllowedParents restricts the types of the parent of the node; empty allows any parent
+	AllowedParents []string
+	// Excludes are types that must not appear anywhere below the node
+	Excludes []string
+}
+
+// ContentModel holds the content rules of node types by name
+type ContentModel map[string]ContentRule
+
+// DefaultContentModel is the content model of the node types of this package
+var DefaultContentModel = ContentModel{
+	"root":      {Children: ChildrenBlock},
+	"paragraph": {Block: true, Children: ChildrenInline},
+	"text":      {Inline: true},
+	"hashtag":   {Inline: true},
+	"mention":   {Inline: true},
+	"link":      {Inline: true, Children: ChildrenInline, Excludes: []string{"link", "autolink"}},
+	"autolink":  {Inline: true, Children: ChildrenInline, Excludes: []string{"link", "autolink"}},
+	"mark":      {Inline: true, Children: ChildrenInline},
+	"decorator": {Inline: true, Block: true},
+}
+
+// ValidateStructure verifies the document follows the default content model
+func ValidateStructure(root *RootNode) error {
+	return DefaultContentModel.Validate(root)
+}
+
+// Validate verifies the root element has type root and every node of the document
+// follows its content rule. Nodes of types missing from the content model are invalid.
+func (cm ContentModel) Validate(root *RootNode) error {
+	if root == nil {
+		return fmt.Errorf("%s: invalid structure: root is nil", pkg)
+	}
+
+	if root.Root.NodeType != "root" {
+		return fmt.Errorf("%s: invalid structure: root type is %q", pkg, root.Root.NodeType)
+	}
+
+	return cm.validate(&root.Root, "root", Path{}, nil)
+}
+
+func (cm ContentModel) validate(node lexical.Node, name string, path Path, excluded []string) error {
+	rule, exists := cm[name]
+	if !exists {
+		return fmt.Errorf("%s: invalid structure at %v: unknown node type %s", pkg, path, name)
+	}
+
+	parent, ok := node.(Parent)
+	if !ok {
+		return nil
+	}
+
+	children := *parent.ChildNodes()
+	if len(children) > 0 && rule.Children == ChildrenNone {
+		return fmt.Errorf("%s: invalid structure at %v: %s cannot have children", pkg, path, name)
+	}
+
+	excluded = append(excluded[:len(excluded):len(excluded)], rule.Excludes...)
+	for i, child := range children {
+		childPath := path.Child(i)
+		if child == nil {
+			return fmt.Errorf("%s: invalid structure at %v: node is nil", pkg, childPath)
+		}
+
+		childName := nodeType(child)
+		childRule, exists := cm[childName]
+		if !exists {
+			return fmt.Errorf("%s: invalid structure at %v: unknown node type %s", pkg, childPath, childName)
+		}
+
+		if slices.Contains(excluded, childName) {
+			return fmt.Errorf("%s: invalid structure at %v: nested %s is not allowed", pkg, childPath, childName)
+		}
+
+		if !rule.allowsChild(childName, childRule) {
+			return fmt.Errorf("%s: invalid structure at %v: %s cannot contain %s", pkg, childPath, name, childName)
+		}
+
+		if len(childRule.AllowedParents) > 0 && !slices.Contains(childRule.AllowedParents, name) {
+			return fmt.Errorf("%s: invalid structure at %v: %s cannot appear in %s", pkg, childPath, childName, name)
+		}
+
+		err := cm.validate(child, childName, childPath, excluded)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cr ContentRule) allowsChild(name string, child ContentRule) bool {
+	if len(cr.AllowedChildren) > 0 && !slices.Contains(cr.AllowedChildren, name) {
+		return false
+	}
+
+	switch cr.Children {
+	case ChildrenInline:
+		return child.Inline
+	case ChildrenBlock:
+		return child.Block
+	case ChildrenAny:
+		return child.Inline || child.Block
+	}
+
+	return false
+}
@@ -0,0 +1,50 @@
+package nodes
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+var _ lexical.Node = &DecoratorNode{}
+
+// DecoratorNode implements the lexical decorator node base type
+type DecoratorNode struct {
+	BaseNode
+}
+
+// Find saves decorator node to nodes if decorator type is in map
+func (dn *DecoratorNode) Find(nodes map[string][]lexical.Node) {
+	Find(dn, nodes)
+}
+
+// TextContentSize returns zero as decorators have no text content
+func (dn *DecoratorNode) TextContentSize() int {
+	return 0
+}
+
+// Type returns type of decorator node
+func (dn DecoratorNode) Type() (string, reflect.Type) {
+	return "decorator", reflect.TypeOf(dn)
+}
+
+// JSONType returns the type of decorator node, which is decoded from JSON directly
+func (dn DecoratorNode) JSONType() reflect.Type {
+	return reflect.TypeOf(dn)
+}
+
+// Unmarshal unmarshals the decorator node
+func (dn *DecoratorNode) Unmarshal(data map[string]interface{}) error {
+	dnB, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(dnB, dn)
+}
+
+// Valid verifies the decorator node is valid
+func (dn *DecoratorNode) Valid() error {
+	return nil
+}
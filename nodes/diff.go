@@ -0,0 +1,440 @@
+package nodes
+
+import (
+	"unicode"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// ChangeKind is the kind of a change between two documents
+type ChangeKind int
+
+const (
+	// BlockInserted is a block added to the root
+	BlockInserted ChangeKind = iota
+	// BlockRemoved is a block removed from the root
+	BlockRemoved
+	// BlockMoved is an unchanged block at a new position in the root
+	BlockMoved
+	// NodeInserted is a node added inside a block
+	NodeInserted
+	// NodeRemoved is a node removed from inside a block
+	NodeRemoved
+	// TextChanged is a change of the text of a text node
+	TextChanged
+	// FormatChanged is a change of the format, style, direction or indent of a node
+	FormatChanged
+	// URLChanged is a change of the url of a link
+	URLChanged
+	// NodeChanged is a change of any other property of a node
+	NodeChanged
+)
+
+var changeKindNames = []string{
+	"block inserted",
+	"block removed",
+	"block moved",
+	"node inserted",
+	"node removed",
+	"text changed",
+	"format changed",
+	"url changed",
+	"node changed",
+}
+
+// String returns the name of the change kind
+func (ck ChangeKind) String() string {
+	if ck < 0 || int(ck) >= len(changeKindNames) {
+		return "unknown"
+	}
+
+	return changeKindNames[ck]
+}
+
+// Change is a single difference between two documents
+type Change struct {
+	Kind ChangeKind
+	// OldPath is the path of the node in the old document; nil for inserted nodes
+	OldPath Path
+	// Path is the path of the node in the new document; nil for removed nodes
+	Path Path
+	Old  lexical.Node
+	New  lexical.Node
+	// Text holds the edits of a TextChanged change
+	Text []TextEdit
+}
+
+// TextOp is the operation of a text edit
+type TextOp int
+
+const (
+	// TextEqual is text present in both versions
+	TextEqual TextOp = iota
+	// TextInsert is text only present in the new version
+	TextInsert
+	// TextDelete is text only present in the old version
+	TextDelete
+)
+
+// TextEdit is a run of text with the same edit operation
+type TextEdit struct {
+	Op   TextOp
+	Text string
+}
+
+// Diff compares two documents and returns the block and inline level changes from old to new
+func Diff(old, new *RootNode) []Change {
+	var changes []Change
+	for _, op := range alignNodes(old.Root.Children, new.Root.Children, true) {
+		oldPath := Path{op.a}
+		newPath := Path{op.b}
+		switch op.kind {
+		case nodeInsert:
+			changes = append(changes, Change{Kind: BlockInserted, Path: newPath, New: new.Root.Children[op.b]})
+		case nodeDelete:
+			changes = append(changes, Change{Kind: BlockRemoved, OldPath: oldPath, Old: old.Root.Children[op.a]})
+		case nodeMove:
+			changes = append(changes, Change{Kind: BlockMoved, OldPath: oldPath, Path: newPath, Old: old.Root.Children[op.a], New: new.Root.Children[op.b]})
+		case nodeModify:
+			changes = append(changes, diffNodes(old.Root.Children[op.a], new.Root.Children[op.b], oldPath, newPath)...)
+		}
+	}
+
+	return changes
+}
+
+func diffNodes(old, new lexical.Node, oldPath, newPath Path) []Change {
+	if Equal(old, new) {
+		return nil
+	}
+
+	change := Change{OldPath: oldPath, Path: newPath, Old: old, New: new}
+
+	oldText, oldIsText := TextNodeOf(old)
+	newText, newIsText := TextNodeOf(new)
+	if oldIsText && newIsText && textOnly(old, new) {
+		var changes []Change
+		if oldText.Text != newText.Text {
+			change.Kind = TextChanged
+			change.Text = diffText(oldText.Text, newText.Text)
+			changes = append(changes, change)
+		}
+
+		if oldText.Format != newText.Format || oldText.Style != newText.Style || oldText.Mode != newText.Mode || oldText.Detail != newText.Detail {
+			change.Kind = FormatChanged
+			change.Text = nil
+			changes = append(changes, change)
+		}
+
+		return changes
+	}
+
+	oldParent, oldIsParent := old.(Parent)
+	newParent, newIsParent := new.(Parent)
+	if !oldIsParent || !newIsParent {
+		change.Kind = NodeChanged
+		return []Change{change}
+	}
+
+	var changes []Change
+	oldShell, newShell := shell(oldParent), shell(newParent)
+	oldLink, oldIsLink := oldShell.(interface{ link() *LinkNode })
+	newLink, newIsLink := newShell.(interface{ link() *LinkNode })
+	if oldIsLink && newIsLink {
+		if oldLink.link().URL != newLink.link().URL {
+			change.Kind = URLChanged
+			changes = append(changes, change)
+			newLink.link().URL = oldLink.link().URL
+		}
+	}
+
+	if !Equal(oldShell, newShell, IgnoreFormat(), IgnoreDirection(), IgnoreIndent()) {
+		change.Kind = NodeChanged
+		changes = append(changes, change)
+	} else if !Equal(oldShell, newShell) {
+		change.Kind = FormatChanged
+		changes = append(changes, change)
+	}
+
+	oldChildren := *oldParent.ChildNodes()
+	newChildren := *newParent.ChildNodes()
+	for _, op := range alignNodes(oldChildren, newChildren, false) {
+		switch op.kind {
+		case nodeInsert:
+			changes = append(changes, Change{Kind: NodeInserted, Path: newPath.Child(op.b), New: newChildren[op.b]})
+		case nodeDelete:
+			changes = append(changes, Change{Kind: NodeRemoved, OldPath: oldPath.Child(op.a), Old: oldChildren[op.a]})
+		case nodeModify:
+			changes = append(changes, diffNodes(oldChildren[op.a], newChildren[op.b], oldPath.Child(op.a), newPath.Child(op.b))...)
+		}
+	}
+
+	return changes
+}
+
+// shell returns a copy of the node without its children
+func shell(node Parent) Parent {
+	clone, _ := Clone(node).(Parent)
+	*clone.ChildNodes() = nil
+	return clone
+}
+
+// textOnly reports whether old and new, text nodes or nodes embedding them, are of the same
+// type and differ at most in their text nodes
+func textOnly(old, new lexical.Node) bool {
+	if nodeType(old) != nodeType(new) {
+		return false
+	}
+
+	clone := Clone(old)
+	oldText, _ := TextNodeOf(clone)
+	newText, _ := TextNodeOf(new)
+	*oldText = *newText
+	return Equal(clone, new)
+}
+
+func nodeType(node lexical.Node) string {
+	name, _ := node.Type()
+	return name
+}
+
+type nodeOpKind int
+
+const (
+	nodeEqual nodeOpKind = iota
+	nodeInsert
+	nodeDelete
+	nodeModify
+	nodeMove
+)
+
+// nodeOp is one step in the alignment of two node arrays.
+// a is the index in the old array and b the index in the new array.
+type nodeOp struct {
+	kind nodeOpKind
+	a, b int
+}
+
+// alignNodes aligns two node arrays. Removed and inserted nodes of the same type
+// between two unchanged nodes are paired as modifications. When moves is true,
+// removed nodes equal to an inserted node are reported as a move at the insert position.
+func alignNodes(old, new lexical.NodeArray, moves bool) []nodeOp {
+	ops := diffSequence(len(old), len(new), func(i, j int) bool {
+		return Equal(old[i], new[j])
+	})
+
+	moved := map[int]int{}
+	if moves {
+		taken := map[int]bool{}
+		for _, del := range ops {
+			if del.kind != nodeDelete {
+				continue
+			}
+
+			for _, ins := range ops {
+				if ins.kind == nodeInsert && !taken[ins.b] && Equal(old[del.a], new[ins.b]) {
+					moved[ins.b] = del.a
+					taken[ins.b] = true
+					break
+				}
+			}
+		}
+	}
+
+	movedFrom := map[int]bool{}
+	for _, a := range moved {
+		movedFrom[a] = true
+	}
+
+	var aligned, dels, inss []nodeOp
+	flush := func() {
+		aligned = append(aligned, pairNodes(old, new, dels, inss)...)
+		dels, inss = nil, nil
+	}
+
+	for _, op := range ops {
+		switch {
+		case op.kind == nodeEqual:
+			flush()
+			aligned = append(aligned, op)
+		case op.kind == nodeDelete && movedFrom[op.a]:
+		case op.kind == nodeDelete:
+			dels = append(dels, op)
+		case op.kind == nodeInsert:
+			if a, ok := moved[op.b]; ok {
+				flush()
+				aligned = append(aligned, nodeOp{kind: nodeMove, a: a, b: op.b})
+				continue
+			}
+
+			inss = append(inss, op)
+		}
+	}
+	flush()
+
+	return aligned
+}
+
+func pairNodes(old, new lexical.NodeArray, dels, inss []nodeOp) []nodeOp {
+	var ops []nodeOp
+	d, i := 0, 0
+	for d < len(dels) {
+		match := -1
+		for j := i; j < len(inss); j++ {
+			if nodeType(old[dels[d].a]) == nodeType(new[inss[j].b]) {
+				match = j
+				break
+			}
+		}
+
+		if match < 0 {
+			ops = append(ops, dels[d])
+			d++
+			continue
+		}
+
+		ops = append(ops, inss[i:match]...)
+		ops = append(ops, nodeOp{kind: nodeModify, a: dels[d].a, b: inss[match].b})
+		i = match + 1
+		d++
+	}
+
+	return append(ops, inss[i:]...)
+}
+
+// diffSequence returns the shortest edit script between two sequences of length n and m
+// using Myers' algorithm. Ops are nodeEqual, nodeDelete or nodeInsert.
+func diffSequence(n, m int, eq func(i, j int) bool) []nodeOp {
+	total := n + m
+	offset := total + 1
+	v := make([]int, 2*total+3)
+	var trace [][]int
+
+	end := -1
+	for d := 0; d <= total && end < 0; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && eq(x, y) {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				end = d
+				break
+			}
+		}
+	}
+
+	var ops []nodeOp
+	x, y := n, m
+	for d := end; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, nodeOp{kind: nodeEqual, a: x, b: y})
+		}
+
+		if x == prevX {
+			y--
+			ops = append(ops, nodeOp{kind: nodeInsert, a: x, b: y})
+		} else {
+			x--
+			ops = append(ops, nodeOp{kind: nodeDelete, a: x, b: y})
+		}
+	}
+
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, nodeOp{kind: nodeEqual, a: x, b: y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
+
+// diffText returns the word level edits from old to new
+func diffText(old, new string) []TextEdit {
+	a, b := tokenize(old), tokenize(new)
+	ops := diffSequence(len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j]
+	})
+
+	var edits []TextEdit
+	for _, op := range ops {
+		var edit TextEdit
+		switch op.kind {
+		case nodeEqual:
+			edit = TextEdit{Op: TextEqual, Text: a[op.a]}
+		case nodeDelete:
+			edit = TextEdit{Op: TextDelete, Text: a[op.a]}
+		case nodeInsert:
+			edit = TextEdit{Op: TextInsert, Text: b[op.b]}
+		}
+
+		if last := len(edits) - 1; last >= 0 && edits[last].Op == edit.Op {
+			edits[last].Text += edit.Text
+			continue
+		}
+
+		edits = append(edits, edit)
+	}
+
+	return edits
+}
+
+// tokenize splits text into runs of letters and digits, runs of spaces and single other characters
+func tokenize(text string) []string {
+	var tokens []string
+	start := -1
+	class := 0
+	for i, r := range text {
+		var c int
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			c = 1
+		case unicode.IsSpace(r):
+			c = 2
+		}
+
+		if start >= 0 && (c != class || c == 0) {
+			tokens = append(tokens, text[start:i])
+			start = -1
+		}
+
+		if start < 0 {
+			start = i
+			class = c
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, text[start:])
+	}
+
+	return tokens
+}
@@ -0,0 +1,487 @@
+package nodes
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+var _ lexical.Node = &DynamicNode{}
+
+// Node base kinds of a NodeSpec
+const (
+	BaseElement   = "element"
+	BaseText      = "text"
+	BaseDecorator = "decorator"
+)
+
+// NodeSpec declares a node type whose nodes are unmarshaled into a DynamicNode
+type NodeSpec struct {
+	// Type is the serialized type name of the node
+	Type string `json:"type"`
+	// Base is the kind of node: element, text or decorator
+	Base string `json:"base"`
+	// Fields are the properties of the node besides those of its base
+	Fields []FieldSpec `json:"fields"`
+	// Children are the allowed child node types of element nodes; empty allows any type
+	Children []string `json:"children"`
+	// Inline is whether element and decorator nodes appear among inline content rather than
+	// among blocks. Text nodes are always inline.
+	Inline bool `json:"inline"`
+}
+
+// FieldSpec declares a property of a node
+type FieldSpec struct {
+	Name string `json:"name"`
+	// Type is the JSON type of the property: string, number, integer, boolean, object, array or any
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+}
+
+var fieldTypes = []string{"string", "number", "integer", "boolean", "object", "array", "any"}
+
+// RegisterSpecs registers the node types declared by a JSON array of node specs
+func RegisterSpecs(data []byte) error {
+	var specs []NodeSpec
+	err := json.Unmarshal(data, &specs)
+	if err != nil {
+		return fmt.Errorf("%s: invalid node specs: %v", pkg, err)
+	}
+
+	for _, spec := range specs {
+		err = RegisterSpec(spec)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterSpecsYAML registers the node types declared by a YAML sequence of node specs.
+// Specs are written in block or flow style, without anchors, tags or multi-line scalars.
+func RegisterSpecsYAML(data []byte) error {
+	specs, err := yamlToJSON(data)
+	if err != nil {
+		return fmt.Errorf("%s: invalid node specs: %v", pkg, err)
+	}
+
+	return RegisterSpecs(specs)
+}
+
+// baseFields holds the serialized property names of each base kind
+var baseFields = map[string][]string{
+	BaseElement:   jsonFields(reflect.TypeOf(ElementNode{})),
+	BaseText:      jsonFields(reflect.TypeOf(TextNode{})),
+	BaseDecorator: jsonFields(reflect.TypeOf(DecoratorNode{})),
+}
+
+// jsonFields returns the JSON names of the fields of a struct type, including embedded fields
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for _, field := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.IsExported() && !field.Anonymous && name != "-" {
+			names = append(names, cmp.Or(name, field.Name))
+		}
+	}
+
+	return names
+}
+
+// RegisterSpec registers the node type declared by spec and adds its content rule to
+// DefaultContentModel
+func RegisterSpec(spec NodeSpec) error {
+	if spec.Type == "" {
+		return fmt.Errorf("%s: node spec is missing type", pkg)
+	}
+
+	switch spec.Base {
+	case BaseElement, BaseText, BaseDecorator:
+	default:
+		return fmt.Errorf("%s: invalid base %q of node spec %s", pkg, spec.Base, spec.Type)
+	}
+
+	for _, field := range spec.Fields {
+		// Properties are matched without regard to case, as encoding/json matches them
+		reserved := slices.ContainsFunc(append(baseFields[spec.Base], "children"), func(name string) bool {
+			return strings.EqualFold(name, field.Name)
+		})
+		if field.Name == "" || reserved {
+			return fmt.Errorf("%s: invalid field name %q of node spec %s", pkg, field.Name, spec.Type)
+		}
+
+		if !slices.Contains(fieldTypes, field.Type) {
+			return fmt.Errorf("%s: invalid type %q of field %s of node spec %s", pkg, field.Type, field.Name, spec.Type)
+		}
+	}
+
+	err := lexical.RegisterNodeFunc(func() lexical.Node {
+		return &DynamicNode{spec: &spec}
+	})
+	if err != nil {
+		return err
+	}
+
+	DefaultContentModel[spec.Type] = spec.contentRule()
+	return nil
+}
+
+// contentRule returns the content rule of the nodes declared by the spec
+func (spec *NodeSpec) contentRule() ContentRule {
+	switch {
+	case spec.Base == BaseText:
+		return ContentRule{Inline: true}
+	case spec.Base == BaseDecorator:
+		return ContentRule{Inline: spec.Inline, Block: !spec.Inline}
+	case spec.Inline:
+		return ContentRule{Inline: true, Children: ChildrenInline, AllowedChildren: spec.Children}
+	}
+
+	return ContentRule{Block: true, Children: ChildrenAny, AllowedChildren: spec.Children}
+}
+
+// DynamicNode is a node of a type declared at runtime by a NodeSpec
+type DynamicNode struct {
+	spec *NodeSpec
+	// Properties holds every serialized property of the node except its children, and
+	// except the text of text nodes
+	Properties map[string]any
+	Children   lexical.NodeArray
+	// textNode holds the text of text nodes, with their text properties
+	textNode *TextNode
+}
+
+// Spec returns the spec declaring the node type
+func (dn *DynamicNode) Spec() *NodeSpec {
+	return dn.spec
+}
+
+// Text returns the text of a dynamic text node
+func (dn *DynamicNode) Text() string {
+	if dn.textNode == nil {
+		return ""
+	}
+
+	return dn.textNode.Text
+}
+
+// text returns the text node of a dynamic text node, or nil for other base kinds
+func (dn *DynamicNode) text() *TextNode {
+	return dn.textNode
+}
+
+// ChildNodes returns the children of the dynamic node
+func (dn *DynamicNode) ChildNodes() *lexical.NodeArray {
+	return &dn.Children
+}
+
+// Clone returns a deep copy of the dynamic node sharing its spec
+func (dn *DynamicNode) Clone() lexical.Node {
+	clone := &DynamicNode{spec: dn.spec}
+	if dn.textNode != nil {
+		tn := *dn.textNode
+		clone.textNode = &tn
+	}
+
+	if dn.Properties != nil {
+		clone.Properties, _ = cloneValue(reflect.ValueOf(dn.Properties)).Interface().(map[string]any)
+	}
+
+	if dn.Children != nil {
+		clone.Children = make(lexical.NodeArray, len(dn.Children))
+		for i, child := range dn.Children {
+			clone.Children[i] = Clone(child)
+		}
+	}
+
+	return clone
+}
+
+// Find saves dynamic node to nodes if its type is in map and then calls find on children
+func (dn *DynamicNode) Find(nodes map[string][]lexical.Node) {
+	Find(dn, nodes)
+
+	for _, child := range dn.Children {
+		child.Find(nodes)
+	}
+}
+
+// TextContentSize returns the length of the text of text nodes and the text content size of the children of element nodes
+func (dn *DynamicNode) TextContentSize() int {
+	if dn.spec != nil && dn.spec.Base == BaseText {
+		return len(dn.Text())
+	}
+
+	size := 0
+	for _, child := range dn.Children {
+		size = size + child.TextContentSize()
+	}
+
+	return size
+}
+
+// Type returns type of dynamic node
+func (dn DynamicNode) Type() (string, reflect.Type) {
+	name := ""
+	if dn.spec != nil {
+		name = dn.spec.Type
+	}
+
+	return name, reflect.TypeOf(dn)
+}
+
+// JSONType returns the type of dynamic node, which is decoded from JSON directly
+func (dn DynamicNode) JSONType() reflect.Type {
+	return reflect.TypeOf(dn)
+}
+
+// Unmarshal unmarshals the dynamic node
+func (dn *DynamicNode) Unmarshal(data map[string]interface{}) error {
+	dnB, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(dnB, dn)
+}
+
+// UnmarshalJSON unmarshals the dynamic node, keeping numbers as json.Number so they round-trip unchanged
+func (dn *DynamicNode) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	dn.Properties = make(map[string]any, len(raw))
+	dn.Children = nil
+	dn.textNode = nil
+	for name, value := range raw {
+		if strings.EqualFold(name, "children") {
+			err = json.Unmarshal(value, &dn.Children)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+		var v any
+		err = decoder.Decode(&v)
+		if err != nil {
+			return err
+		}
+
+		// The text of text nodes is held by their text node
+		if dn.spec != nil && dn.spec.Base == BaseText && strings.EqualFold(name, "text") {
+			dn.textNode = &TextNode{}
+			err = json.Unmarshal(data, dn.textNode)
+			if err != nil {
+				return err
+			}
+			dn.textNode.NodeType = "text"
+			continue
+		}
+
+		dn.Properties[name] = v
+	}
+
+	return nil
+}
+
+// MarshalJSON marshals the dynamic node with its properties and children
+func (dn DynamicNode) MarshalJSON() ([]byte, error) {
+	data := maps.Clone(dn.Properties)
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	if dn.spec != nil {
+		data["type"] = dn.spec.Type
+	}
+
+	if dn.textNode != nil {
+		data["text"] = dn.textNode.Text
+	}
+
+	if dn.Children != nil || (dn.spec != nil && dn.spec.Base == BaseElement) {
+		children := dn.Children
+		if children == nil {
+			children = lexical.NodeArray{}
+		}
+		data["children"] = children
+	}
+
+	return json.Marshal(data)
+}
+
+// JSONSchema returns the schema of the serialized dynamic node
+func (dn *DynamicNode) JSONSchema() map[string]any {
+	properties := map[string]any{
+		"type":    map[string]any{"const": dn.spec.Type},
+		"version": map[string]any{"type": "integer"},
+	}
+	required := []string{"type"}
+
+	switch dn.spec.Base {
+	case BaseElement:
+		for name, schema := range (&ElementNode{}).SchemaProperties() {
+			properties[name] = schema
+		}
+		properties["indent"] = map[string]any{"type": "integer"}
+		items := map[string]any{"$ref": lexical.SchemaRef("")}
+		if len(dn.spec.Children) > 0 {
+			var refs []any
+			for _, child := range dn.spec.Children {
+				refs = append(refs, map[string]any{"$ref": lexical.SchemaRef(child)})
+			}
+			items = map[string]any{"anyOf": refs}
+		}
+		properties["children"] = map[string]any{"type": "array", "items": items}
+	case BaseText:
+		properties["text"] = map[string]any{"type": "string"}
+	}
+
+	for _, field := range dn.spec.Fields {
+		schema := map[string]any{}
+		if field.Type != "any" {
+			schema["type"] = field.Type
+		}
+		properties[field.Name] = schema
+
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// Valid verifies the dynamic node is valid
+func (dn *DynamicNode) Valid() error {
+	err := runDynamicNodeValFuncs(
+		dn,
+		dynamicNodeRequireFields,
+		dynamicNodeRequireElement,
+		dynamicNodeRequireChildren,
+		dynamicNodeChildrenValid,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: invalid %s node: %v", pkg, dn.spec.Type, err)
+	}
+
+	return nil
+}
+
+type dynamicNodeValFunc func(*DynamicNode) error
+
+func runDynamicNodeValFuncs(node *DynamicNode, fns ...dynamicNodeValFunc) error {
+	if node == nil || node.spec == nil {
+		return fmt.Errorf("node is nil")
+	}
+
+	for _, fn := range fns {
+		err := fn(node)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func dynamicNodeRequireFields(node *DynamicNode) error {
+	for _, field := range node.spec.Fields {
+		value, exists := node.Properties[field.Name]
+		if !exists || value == nil {
+			if field.Required {
+				return fmt.Errorf("missing field %s", field.Name)
+			}
+			continue
+		}
+
+		if !fieldTypeMatches(field.Type, value) {
+			return fmt.Errorf("invalid type of field %s", field.Name)
+		}
+	}
+
+	return nil
+}
+
+func fieldTypeMatches(fieldType string, value any) bool {
+	switch v := value.(type) {
+	case string:
+		return fieldType == "string" || fieldType == "any"
+	case bool:
+		return fieldType == "boolean" || fieldType == "any"
+	case json.Number:
+		_, err := v.Int64()
+		return fieldType == "number" || fieldType == "any" || (fieldType == "integer" && err == nil)
+	case float64:
+		return fieldType == "number" || fieldType == "any" || (fieldType == "integer" && v == float64(int64(v)))
+	case int:
+		return fieldType == "number" || fieldType == "integer" || fieldType == "any"
+	case map[string]any:
+		return fieldType == "object" || fieldType == "any"
+	case []any:
+		return fieldType == "array" || fieldType == "any"
+	}
+
+	return fieldType == "any"
+}
+
+func dynamicNodeRequireElement(node *DynamicNode) error {
+	switch node.spec.Base {
+	case BaseElement:
+		element := &ElementNode{}
+		if direction, ok := node.Properties["direction"].(string); ok {
+			element.Direction = &direction
+		}
+		element.Format, _ = node.Properties["format"].(string)
+		return runElementNodeValFuncs(element, elementNodeRequireDirection, elementNodeRequireFormat)
+	case BaseText:
+		if node.textNode == nil {
+			return fmt.Errorf("missing text")
+		}
+	}
+
+	if len(node.Children) > 0 && node.spec.Base != BaseElement {
+		return fmt.Errorf("%s node cannot have children", node.spec.Base)
+	}
+
+	return nil
+}
+
+func dynamicNodeRequireChildren(node *DynamicNode) error {
+	if len(node.spec.Children) == 0 {
+		return nil
+	}
+
+	for _, child := range node.Children {
+		if !slices.Contains(node.spec.Children, nodeType(child)) {
+			return fmt.Errorf("invalid child type %s", nodeType(child))
+		}
+	}
+
+	return nil
+}
+
+func dynamicNodeChildrenValid(node *DynamicNode) error {
+	for _, child := range node.Children {
+		err := child.Valid()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
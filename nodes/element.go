@@ -4,12 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 
 	"github.com/tylertravisty/go-lexical"
 )
 
 var _ lexical.Node = &ElementNode{}
 
+var (
+	elementDirections = []string{"ltr", "rtl"}
+	elementFormats    = []string{"left", "start", "center", "right", "end", "justify", ""}
+)
+
 // ElementNode implements the lexical element node type
 type ElementNode struct {
 	BaseNode
@@ -19,6 +25,16 @@ type ElementNode struct {
 	Indent    int               `json:"indent"`
 }
 
+// ChildNodes returns the children of the element node
+func (en *ElementNode) ChildNodes() *lexical.NodeArray {
+	return &en.Children
+}
+
+// element returns the element node, including elements embedded in other node types
+func (en *ElementNode) element() *ElementNode {
+	return en
+}
+
 // Find saves element node to nodes if element type is in map and then calls find on children
 func (en *ElementNode) Find(nodes map[string][]lexical.Node) {
 	Find(en, nodes)
@@ -38,11 +54,34 @@ func (en ElementNode) TextContentSize() int {
 	return size
 }
 
+// SchemaProperties returns the allowed values of the element direction and format
+func (en *ElementNode) SchemaProperties() map[string]map[string]any {
+	directions := []any{nil}
+	for _, direction := range elementDirections {
+		directions = append(directions, direction)
+	}
+
+	formats := []any{}
+	for _, format := range elementFormats {
+		formats = append(formats, format)
+	}
+
+	return map[string]map[string]any{
+		"direction": {"enum": directions},
+		"format":    {"enum": formats},
+	}
+}
+
 // Type returns type of element node
 func (en ElementNode) Type() (string, reflect.Type) {
 	return "element", reflect.TypeOf(en)
 }
 
+// JSONType returns the type of element node, which is decoded from JSON directly
+func (en ElementNode) JSONType() reflect.Type {
+	return reflect.TypeOf(en)
+}
+
 // Unmarshal unmarshals the element node
 func (en *ElementNode) Unmarshal(data map[string]any) error {
 	enB, err := json.Marshal(data)
@@ -90,9 +129,7 @@ func elementNodeRequireDirection(node *ElementNode) error {
 		return nil
 	}
 
-	switch *node.Direction {
-	case "ltr", "rtl":
-	default:
+	if !slices.Contains(elementDirections, *node.Direction) {
 		return fmt.Errorf("invalid direction")
 	}
 
@@ -100,9 +137,7 @@ func elementNodeRequireDirection(node *ElementNode) error {
 }
 
 func elementNodeRequireFormat(node *ElementNode) error {
-	switch node.Format {
-	case "left", "start", "center", "right", "end", "justify", "":
-	default:
+	if !slices.Contains(elementFormats, node.Format) {
 		return fmt.Errorf("invalid format")
 	}
 
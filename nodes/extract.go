@@ -0,0 +1,183 @@
+package nodes
+
+import (
+	"fmt"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// Extract returns a new document holding the range of root between start and end, with text
+// offsets counted in UTF-16 code units as in Lexical
+func Extract(root *RootNode, start, end Position) (*RootNode, error) {
+	return NewTextIndex(root, UTF16).Extract(start, end)
+}
+
+// Extract returns a new document holding the range of the indexed document between start
+// and end. Text nodes are split at the boundaries of the range and keep their format and
+// style. Partially covered elements, such as the paragraphs and links around the range, are
+// kept with only their covered children, except inline elements left without children.
+func (ti *TextIndex) Extract(start, end Position) (*RootNode, error) {
+	startOffset, err := ti.Offset(start)
+	if err != nil {
+		return nil, err
+	}
+
+	endOffset, err := ti.Offset(end)
+	if err != nil {
+		return nil, err
+	}
+
+	if startOffset > endOffset {
+		return nil, fmt.Errorf("%s: range start %v:%d is after end %v:%d", pkg, start.Path, start.Offset, end.Path, end.Offset)
+	}
+
+	e := extractor{start: start, end: end, unit: ti.unit}
+	root, _ := shell(&ti.root.Root).(*ElementNode)
+	root.Children = e.extractChildren(ti.root.Root.Children, Path{})
+	return &RootNode{Root: *root}, nil
+}
+
+// pointPlace is where a position is relative to a node
+type pointPlace int
+
+const (
+	pointBefore pointPlace = iota
+	pointInside
+	pointAfter
+)
+
+type extractor struct {
+	start Position
+	end   Position
+	unit  Unit
+}
+
+func (e *extractor) extractChildren(children lexical.NodeArray, path Path) lexical.NodeArray {
+	extracted := lexical.NodeArray{}
+	for i, child := range children {
+		node := e.extract(child, path.Child(i))
+		if node != nil {
+			extracted = append(extracted, node)
+		}
+	}
+
+	return extracted
+}
+
+// extract returns the part of node at path inside the range, or nil if it is outside
+func (e *extractor) extract(node lexical.Node, path Path) lexical.Node {
+	start, end := place(e.start, path), place(e.end, path)
+	if start == pointAfter || end == pointBefore {
+		return nil
+	}
+
+	if start == pointBefore && end == pointAfter {
+		return Clone(node)
+	}
+
+	if tn, ok := TextNodeOf(node); ok {
+		return e.extractText(node, tn, path)
+	}
+
+	switch n := node.(type) {
+	case Parent:
+		clone := shell(n)
+		*clone.ChildNodes() = e.extractChildren(*n.ChildNodes(), path)
+		if len(*clone.ChildNodes()) == 0 && !blockElement(clone) {
+			return nil
+		}
+
+		return clone
+	}
+
+	return Clone(node)
+}
+
+func (e *extractor) extractText(node lexical.Node, tn *TextNode, path Path) lexical.Node {
+	lo, hi, ok := textRange(tn, path, e.start, e.end, e.unit)
+	if !ok {
+		return nil
+	}
+
+	return textPart(node, tn, tn.Text[lo:hi])
+}
+
+// textPart returns a copy of node holding part of the text of its text node tn. Parts that
+// are not valid as node, such as a hashtag without its hash sign, are plain text nodes.
+func textPart(node lexical.Node, tn *TextNode, text string) lexical.Node {
+	part := withText(node, text)
+	if part.Valid() == nil {
+		return part
+	}
+
+	plain := withText(tn, text)
+	plain.NodeType = "text"
+	return plain
+}
+
+// textRange returns the non-empty byte range of the text of tn at path between start and end
+func textRange(tn *TextNode, path Path, start, end Position, unit Unit) (int, int, bool) {
+	lo, hi := 0, len(tn.Text)
+	if start.Path.Compare(path) == 0 {
+		lo = byteOffset(tn.Text, start.Offset, unit)
+	}
+
+	if end.Path.Compare(path) == 0 {
+		hi = byteOffset(tn.Text, end.Offset, unit)
+	}
+
+	if lo < 0 || hi < 0 || lo >= hi {
+		return 0, 0, false
+	}
+
+	return lo, hi, true
+}
+
+// place returns where pos is relative to the node at path. A text position is inside its
+// text node and its ancestors. An element position, the boundary before child Offset of the
+// element at Path, is inside the element and its ancestors.
+func place(pos Position, path Path) pointPlace {
+	if len(pos.Path) >= len(path) && pos.Path[:len(path)].Compare(path) == 0 {
+		return pointInside
+	}
+
+	if len(path) > len(pos.Path) && path[:len(pos.Path)].Compare(pos.Path) == 0 {
+		if path[len(pos.Path)] >= pos.Offset {
+			return pointBefore
+		}
+
+		return pointAfter
+	}
+
+	if pos.Path.Compare(path) < 0 {
+		return pointBefore
+	}
+
+	return pointAfter
+}
+
+// byteOffset returns the byte offset in s of the offset in unit, or -1 if it is out of range
+// or splits a UTF-16 surrogate pair
+func byteOffset(s string, offset int, unit Unit) int {
+	n := 0
+	for i, r := range s {
+		if n == offset {
+			return i
+		}
+
+		n++
+		if unit == UTF16 && r >= 0x10000 {
+			n++
+		}
+
+		if n > offset {
+			return -1
+		}
+	}
+
+	if n == offset {
+		return len(s)
+	}
+
+	return -1
+}
@@ -0,0 +1,71 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+var _ lexical.Node = &HashtagNode{}
+
+// HashtagNode implements the lexical hashtag node type
+type HashtagNode struct {
+	TextNode
+}
+
+// NewHashtagNode returns a hashtag node holding text, such as "#lexical"
+func NewHashtagNode(text string) *HashtagNode {
+	return &HashtagNode{
+		TextNode: TextNode{
+			BaseNode: BaseNode{NodeType: "hashtag", Version: 1},
+			Mode:     "normal",
+			Text:     text,
+		},
+	}
+}
+
+// Find saves hashtag node to nodes if hashtag type is in map
+func (hn *HashtagNode) Find(nodes map[string][]lexical.Node) {
+	Find(hn, nodes)
+}
+
+// Type returns type of hashtag node
+func (hn HashtagNode) Type() (string, reflect.Type) {
+	return "hashtag", reflect.TypeOf(hn)
+}
+
+// textEntity marks hashtag nodes as text entities, whose text is not edited with the text
+// around them
+func (hn *HashtagNode) textEntity() {}
+
+// JSONType returns the type of hashtag node, which is decoded from JSON directly
+func (hn HashtagNode) JSONType() reflect.Type {
+	return reflect.TypeOf(hn)
+}
+
+// Unmarshal unmarshals the hashtag node
+func (hn *HashtagNode) Unmarshal(data map[string]interface{}) error {
+	hnB, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(hnB, hn)
+}
+
+// Valid verifies the hashtag node is a valid text node whose text starts with a hash sign
+func (hn *HashtagNode) Valid() error {
+	err := hn.TextNode.Valid()
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(hn.Text, "#") && !strings.HasPrefix(hn.Text, "＃") {
+		return fmt.Errorf("%s: invalid hashtag node: text does not start with a hash sign", pkg)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package nodes
+
+import (
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// HTMLRenderer is implemented by nodes that render themselves as HTML.
+// children holds the rendered children of element nodes.
+type HTMLRenderer interface {
+	HTML(children string) string
+}
+
+// htmlURLSchemes are the schemes of the link URLs rendered as HTML
+var htmlURLSchemes = []string{"http", "https", "mailto", "tel"}
+
+var textFormatTags = []struct {
+	format int
+	tag    string
+}{
+	{FormatBold, "strong"},
+	{FormatItalic, "em"},
+	{FormatStrikethrough, "s"},
+	{FormatUnderline, "u"},
+	{FormatCode, "code"},
+	{FormatSubscript, "sub"},
+	{FormatSuperscript, "sup"},
+	{FormatHighlight, "mark"},
+}
+
+// HTML renders the document as HTML
+func HTML(root *RootNode) string {
+	var b strings.Builder
+	for _, child := range root.Root.Children {
+		writeHTML(&b, child)
+	}
+
+	return b.String()
+}
+
+func writeHTML(b *strings.Builder, node lexical.Node) {
+	var children strings.Builder
+	if parent, ok := node.(Parent); ok {
+		for _, child := range *parent.ChildNodes() {
+			writeHTML(&children, child)
+		}
+	}
+
+	if renderer, ok := node.(HTMLRenderer); ok {
+		b.WriteString(renderer.HTML(children.String()))
+		return
+	}
+
+	if tn, ok := TextNodeOf(node); ok {
+		writeTextHTML(b, tn)
+		return
+	}
+
+	switch n := node.(type) {
+	case *ParagraphNode:
+		b.WriteString("<p>")
+		if children.Len() == 0 {
+			b.WriteString("<br>")
+		}
+		b.WriteString(children.String())
+		b.WriteString("</p>")
+	case *DecoratorNode:
+		b.WriteString(`<span data-lexical-decorator="true"></span>`)
+	case *MarkNode:
+		tag := "mark"
+		switch {
+		case slices.Contains(n.IDs, RedlineInsert):
+			tag = "ins"
+		case slices.Contains(n.IDs, RedlineDelete):
+			tag = "del"
+		}
+		b.WriteString("<" + tag + ">")
+		b.WriteString(children.String())
+		b.WriteString("</" + tag + ">")
+	case interface{ link() *LinkNode }:
+		ln := n.link()
+		b.WriteString(`<a href="` + html.EscapeString(sanitizeURL(ln.URL)) + `"`)
+		writeAttrHTML(b, "rel", ln.Rel)
+		writeAttrHTML(b, "target", ln.Target)
+		writeAttrHTML(b, "title", ln.Title)
+		b.WriteString(">")
+		b.WriteString(children.String())
+		b.WriteString("</a>")
+	default:
+		b.WriteString(children.String())
+	}
+}
+
+// sanitizeURL returns rawURL if it is relative or of a scheme of htmlURLSchemes, and
+// about:blank otherwise, as sanitizeUrl of the Lexical playground, so that links such as
+// javascript: URLs of untrusted documents are not rendered
+func sanitizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "about:blank"
+	}
+
+	if u.Scheme != "" && !slices.Contains(htmlURLSchemes, strings.ToLower(u.Scheme)) {
+		return "about:blank"
+	}
+
+	return rawURL
+}
+
+func writeAttrHTML(b *strings.Builder, name string, value *string) {
+	if value == nil {
+		return
+	}
+
+	b.WriteString(" " + name + `="` + html.EscapeString(*value) + `"`)
+}
+
+func writeTextHTML(b *strings.Builder, tn *TextNode) {
+	var open, close []string
+	for _, ft := range textFormatTags {
+		if tn.Format&ft.format != 0 {
+			open = append(open, "<"+ft.tag+">")
+			close = append([]string{"</" + ft.tag + ">"}, close...)
+		}
+	}
+
+	if tn.Style != "" {
+		open = append(open, `<span style="`+html.EscapeString(tn.Style)+`">`)
+		close = append([]string{"</span>"}, close...)
+	}
+
+	b.WriteString(strings.Join(open, ""))
+	b.WriteString(html.EscapeString(tn.Text))
+	b.WriteString(strings.Join(close, ""))
+}
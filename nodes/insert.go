@@ -0,0 +1,130 @@
+package nodes
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// Concat returns a document holding copies of the blocks of docs in order, normalized.
+// The root of the result is a copy of the root of the first document.
+func Concat(docs ...*RootNode) *RootNode {
+	root := &RootNode{Root: ElementNode{BaseNode: BaseNode{NodeType: "root", Version: 1}}}
+	if len(docs) > 0 {
+		shell, _ := shell(&docs[0].Root).(*ElementNode)
+		root.Root = *shell
+	}
+
+	root.Root.Children = lexical.NodeArray{}
+	for _, doc := range docs {
+		root.Root.Children = append(root.Root.Children, cloneNodes(doc.Root.Children)...)
+	}
+
+	Normalize(root)
+	return root
+}
+
+// InsertDocument inserts copies of the blocks of source into target at pos, with text offsets
+// counted in UTF-16 code units, and normalizes target.
+//
+// At a block boundary, an element position of the root, the blocks are inserted between the
+// blocks of target. Inside a paragraph, the paragraph is split at pos as when pasting in
+// Lexical: the content of the first inserted paragraph joins the head of the split paragraph
+// and the tail joins the content of the last inserted paragraph.
+func InsertDocument(target *RootNode, pos Position, source *RootNode) error {
+	index := NewTextIndex(target, UTF16)
+	_, err := index.Offset(pos)
+	if err != nil {
+		return err
+	}
+
+	blocks := cloneNodes(source.Root.Children)
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	children := target.Root.Children
+	if len(pos.Path) == 0 {
+		target.Root.Children = slices.Insert(children, pos.Offset, blocks...)
+		Normalize(target)
+		return nil
+	}
+
+	i := pos.Path[0]
+	if !inlineContainer(children[i]) {
+		at := i + 1
+		if len(pos.Path) == 1 && pos.Offset == 0 {
+			at = i
+		}
+
+		target.Root.Children = slices.Insert(children, at, blocks...)
+		Normalize(target)
+		return nil
+	}
+
+	head, err := index.Extract(Position{Path: Path{}, Offset: i}, pos)
+	if err != nil {
+		return err
+	}
+
+	tail, err := index.Extract(pos, Position{Path: Path{}, Offset: i + 1})
+	if err != nil {
+		return err
+	}
+
+	if len(head.Root.Children) != 1 || len(tail.Root.Children) != 1 {
+		return fmt.Errorf("%s: cannot split block at %v:%d", pkg, pos.Path, pos.Offset)
+	}
+
+	target.Root.Children = slices.Replace(children, i, i+1, splice(head.Root.Children[0], blocks, tail.Root.Children[0])...)
+	Normalize(target)
+	return nil
+}
+
+// splice returns the blocks replacing a paragraph split into head and tail when blocks are
+// inserted between them
+func splice(head lexical.Node, blocks lexical.NodeArray, tail lexical.Node) lexical.NodeArray {
+	headChildren := head.(Parent).ChildNodes()
+	tailChildren := tail.(Parent).ChildNodes()
+
+	mergedHead := false
+	if len(blocks) > 0 && inlineContainer(blocks[0]) {
+		*headChildren = append(*headChildren, *blocks[0].(Parent).ChildNodes()...)
+		blocks = blocks[1:]
+		mergedHead = true
+	}
+
+	if len(blocks) == 0 && mergedHead {
+		*headChildren = append(*headChildren, *tailChildren...)
+		return lexical.NodeArray{head}
+	}
+
+	spliced := lexical.NodeArray{}
+	if mergedHead || len(*headChildren) > 0 {
+		spliced = append(spliced, head)
+	}
+
+	if last := len(blocks) - 1; last >= 0 && inlineContainer(blocks[last]) {
+		lastChildren := blocks[last].(Parent).ChildNodes()
+		*lastChildren = append(*lastChildren, *tailChildren...)
+		return append(spliced, blocks...)
+	}
+
+	spliced = append(spliced, blocks...)
+	if len(*tailChildren) > 0 || len(spliced) == 0 {
+		spliced = append(spliced, tail)
+	}
+
+	return spliced
+}
+
+// inlineContainer reports whether node is a block holding inline content, such as a paragraph
+func inlineContainer(node lexical.Node) bool {
+	if _, ok := node.(Parent); !ok {
+		return false
+	}
+
+	rule, exists := DefaultContentModel[nodeType(node)]
+	return exists && rule.Block && rule.Children == ChildrenInline
+}
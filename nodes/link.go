@@ -28,11 +28,21 @@ func (ln *LinkNode) Find(nodes map[string][]lexical.Node) {
 	}
 }
 
+// link returns the link node, including links embedded in other node types
+func (ln *LinkNode) link() *LinkNode {
+	return ln
+}
+
 // Type returns type of link node
 func (ln LinkNode) Type() (string, reflect.Type) {
 	return "link", reflect.TypeOf(ln)
 }
 
+// JSONType returns the type of link node, which is decoded from JSON directly
+func (ln LinkNode) JSONType() reflect.Type {
+	return reflect.TypeOf(ln)
+}
+
 // Unmarshal unmarshals the link node
 func (ln *LinkNode) Unmarshal(data map[string]interface{}) error {
 	lnB, err := json.Marshal(data)
@@ -43,7 +53,8 @@ func (ln *LinkNode) Unmarshal(data map[string]interface{}) error {
 	return json.Unmarshal(lnB, ln)
 }
 
-// Valid verifies the link node is valid
+// Valid verifies the link node has inline children, no nested links and text.
+// Children of types missing from DefaultContentModel are assumed to be inline.
 func (ln *LinkNode) Valid() error {
 	err := ln.ElementNode.Valid()
 	if err != nil {
@@ -52,7 +63,9 @@ func (ln *LinkNode) Valid() error {
 
 	err = runLinkNodeValFuncs(
 		ln,
-		linkNodeRequireTextChild,
+		linkNodeRequireInlineChildren,
+		linkNodeRequireNoNestedLinks,
+		linkNodeRequireText,
 	)
 	if err != nil {
 		return fmt.Errorf("%s: invalid link node: %v", pkg, err)
@@ -78,16 +91,47 @@ func runLinkNodeValFuncs(node *LinkNode, fns ...linkNodeValFunc) error {
 	return nil
 }
 
-func linkNodeRequireTextChild(node *LinkNode) error {
-	if len(node.Children) != 1 {
+func linkNodeRequireInlineChildren(node *LinkNode) error {
+	if len(node.Children) == 0 {
 		return fmt.Errorf("invalid number of children")
 	}
 
-	child := node.Children[0]
-	cType, _ := child.Type()
-	expectedType, _ := TextNode{}.Type()
-	if cType != expectedType {
-		return fmt.Errorf("invalid child type")
+	for _, child := range node.Children {
+		if child == nil {
+			return fmt.Errorf("child is nil")
+		}
+
+		rule, exists := DefaultContentModel[nodeType(child)]
+		if exists && !rule.Inline {
+			return fmt.Errorf("invalid child type")
+		}
+	}
+
+	return nil
+}
+
+func linkNodeRequireNoNestedLinks(node *LinkNode) error {
+	var nested bool
+	for _, child := range node.Children {
+		walk(child, nil, func(n lexical.Node, _ Path) bool {
+			if _, ok := n.(interface{ link() *LinkNode }); ok {
+				nested = true
+			}
+
+			return !nested
+		})
+
+		if nested {
+			return fmt.Errorf("nested link")
+		}
+	}
+
+	return nil
+}
+
+func linkNodeRequireText(node *LinkNode) error {
+	if node.TextContentSize() == 0 {
+		return fmt.Errorf("empty text")
 	}
 
 	return nil
@@ -0,0 +1,119 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+var _ lexical.Node = &MarkNode{}
+
+// MarkNode implements the lexical mark node type
+type MarkNode struct {
+	ElementNode
+	IDs []string `json:"ids"`
+}
+
+// NewMarkNode returns a mark node with the given ids wrapping children
+func NewMarkNode(ids []string, children ...lexical.Node) *MarkNode {
+	return &MarkNode{
+		ElementNode: ElementNode{
+			BaseNode: BaseNode{NodeType: "mark", Version: 1},
+			Children: children,
+		},
+		IDs: ids,
+	}
+}
+
+// Find saves mark node to nodes if mark type is in map and then calls find on children
+func (mn *MarkNode) Find(nodes map[string][]lexical.Node) {
+	Find(mn, nodes)
+
+	for _, child := range mn.Children {
+		child.Find(nodes)
+	}
+}
+
+// Type returns type of mark node
+func (mn MarkNode) Type() (string, reflect.Type) {
+	return "mark", reflect.TypeOf(mn)
+}
+
+// JSONType returns the type of mark node, which is decoded from JSON directly
+func (mn MarkNode) JSONType() reflect.Type {
+	return reflect.TypeOf(mn)
+}
+
+// Unmarshal unmarshals the mark node
+func (mn *MarkNode) Unmarshal(data map[string]interface{}) error {
+	mnB, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(mnB, mn)
+}
+
+// MarkRange wraps the text of root between start and end in mark nodes with the given ids,
+// splitting text nodes at the boundaries of the range. Text offsets are counted in UTF-16
+// code units, as in Lexical.
+func MarkRange(root *RootNode, start, end Position, ids []string) error {
+	index := NewTextIndex(root, UTF16)
+	startOffset, err := index.Offset(start)
+	if err != nil {
+		return err
+	}
+
+	endOffset, err := index.Offset(end)
+	if err != nil {
+		return err
+	}
+
+	if startOffset > endOffset {
+		return fmt.Errorf("%s: range start %v:%d is after end %v:%d", pkg, start.Path, start.Offset, end.Path, end.Offset)
+	}
+
+	markChildren(&root.Root, Path{}, start, end, ids)
+	return nil
+}
+
+func markChildren(node Parent, path Path, start, end Position, ids []string) {
+	children := node.ChildNodes()
+	marked := make(lexical.NodeArray, 0, len(*children))
+	for i, child := range *children {
+		childPath := path.Child(i)
+		if place(start, childPath) == pointAfter || place(end, childPath) == pointBefore {
+			marked = append(marked, child)
+			continue
+		}
+
+		tn, isText := TextNodeOf(child)
+		parent, isParent := child.(Parent)
+		switch {
+		case isText:
+			lo, hi, ok := textRange(tn, childPath, start, end, UTF16)
+			if !ok {
+				marked = append(marked, child)
+				continue
+			}
+
+			if lo > 0 {
+				marked = append(marked, textPart(child, tn, tn.Text[:lo]))
+			}
+			marked = append(marked, NewMarkNode(slices.Clone(ids), textPart(child, tn, tn.Text[lo:hi])))
+			if hi < len(tn.Text) {
+				marked = append(marked, textPart(child, tn, tn.Text[hi:]))
+			}
+		case isParent:
+			markChildren(parent, childPath, start, end, ids)
+			marked = append(marked, child)
+		default:
+			marked = append(marked, child)
+		}
+	}
+
+	*children = marked
+}
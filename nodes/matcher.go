@@ -0,0 +1,281 @@
+package nodes
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+var (
+	// URLRegexp matches URLs, as the URL matcher of the Lexical AutoLinkPlugin documentation
+	URLRegexp = regexp.MustCompile(`((https?://(www\.)?)|(www\.))[-a-zA-Z0-9@:%._+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b([-a-zA-Z0-9()@:%_+.~#?&/=]*)`)
+	// EmailRegexp matches email addresses, as the email matcher of the Lexical AutoLinkPlugin documentation
+	EmailRegexp = regexp.MustCompile(`(([^<>()\[\]\\.,;:\s@"]+(\.[^<>()\[\]\\.,;:\s@"]+)*)|(".+"))@((\[[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\])|(([a-zA-Z\-0-9]+\.)+[a-zA-Z]{2,}))`)
+
+	hashtagRegexp = regexp.MustCompile(`(?:^|[^\p{L}\p{M}\p{N}_&])([#＃][\p{L}\p{M}\p{N}_]*[\p{L}\p{M}_][\p{L}\p{M}\p{N}_]*)`)
+	mentionRegexp = regexp.MustCompile(`(?:^|[^\p{L}\p{M}\p{N}_@.])(@([\p{L}\p{M}\p{N}_](?:[\p{L}\p{M}\p{N}_.-]*[\p{L}\p{M}\p{N}_])?))`)
+)
+
+// MatcherResult is a match of a text matcher
+type MatcherResult struct {
+	// Start and End are the byte offsets of the match in the matched text
+	Start int
+	End   int
+	// Node returns the node replacing the matched text, given the text nodes holding it
+	Node func(children []*TextNode) lexical.Node
+}
+
+// TextMatcher returns the non-overlapping matches of text in order
+type TextMatcher func(text string) []MatcherResult
+
+// DefaultTextMatchers returns the URL, email, hashtag and mention matchers
+func DefaultTextMatchers() []TextMatcher {
+	return []TextMatcher{URLMatcher(), EmailMatcher(), HashtagMatcher(), MentionMatcher()}
+}
+
+// URLMatcher returns a link matcher of URLRegexp linking URLs without a scheme with https.
+// Sentence punctuation ending a URL, as in "see www.example.com.", is left out of the link.
+func URLMatcher() TextMatcher {
+	return linkMatcher(URLRegexp, func(text string) string {
+		if strings.HasPrefix(text, "http") {
+			return text
+		}
+
+		return "https://" + text
+	}, true)
+}
+
+// EmailMatcher returns a link matcher of EmailRegexp linking addresses with mailto
+func EmailMatcher() TextMatcher {
+	return LinkMatcherWithRegexp(EmailRegexp, func(text string) string {
+		return "mailto:" + text
+	})
+}
+
+// LinkMatcherWithRegexp returns a matcher wrapping the matches of re in autolink nodes, as
+// createLinkMatcherWithRegExp of Lexical. The url of a link is urlTransformer of the matched
+// text, or the matched text when urlTransformer is nil. As in the Lexical AutoLinkPlugin,
+// matches must be preceded and followed by a separator or the ends of the text.
+func LinkMatcherWithRegexp(re *regexp.Regexp, urlTransformer func(text string) string) TextMatcher {
+	return linkMatcher(re, urlTransformer, false)
+}
+
+// linkMatcher returns LinkMatcherWithRegexp of re and urlTransformer. If trimPunctuation,
+// trailing sentence punctuation of matches is left out of links, and the separator must
+// follow the punctuation.
+func linkMatcher(re *regexp.Regexp, urlTransformer func(text string) string, trimPunctuation bool) TextMatcher {
+	return func(text string) []MatcherResult {
+		var results []MatcherResult
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if trimPunctuation {
+				end = start + len(strings.TrimRight(text[start:end], sentencePunctuation))
+			}
+
+			if start == end || !separatorBefore(text, start) || !separatorAfter(text, loc[1]) {
+				continue
+			}
+
+			url := text[start:end]
+			if urlTransformer != nil {
+				url = urlTransformer(url)
+			}
+
+			results = append(results, MatcherResult{
+				Start: start,
+				End:   end,
+				Node: func(children []*TextNode) lexical.Node {
+					link := NewAutoLinkNode(url)
+					link.Children = appendText(link.Children, children)
+					return link
+				},
+			})
+		}
+
+		return results
+	}
+}
+
+// separatorBefore reports whether offset of text is at its start or after a separator
+func separatorBefore(text string, offset int) bool {
+	r, _ := utf8.DecodeLastRuneInString(text[:offset])
+	return offset == 0 || isLinkSeparator(r)
+}
+
+// separatorAfter reports whether offset of text is at its end or before a separator
+func separatorAfter(text string, offset int) bool {
+	r, _ := utf8.DecodeRuneInString(text[offset:])
+	return offset == len(text) || isLinkSeparator(r)
+}
+
+// sentencePunctuation holds the punctuation that can end a sentence after a URL
+const sentencePunctuation = ".,;:!?"
+
+func isLinkSeparator(r rune) bool {
+	return r == '.' || r == ',' || r == ';' || unicode.IsSpace(r)
+}
+
+// HashtagMatcher returns a matcher of hashtags, such as "#lexical", replacing them with
+// hashtag nodes. Hashtags hold at least one letter and follow no letter or digit.
+func HashtagMatcher() TextMatcher {
+	return submatchMatcher(hashtagRegexp, func(text string, loc []int, children []*TextNode) lexical.Node {
+		return withFormat(NewHashtagNode(text[loc[2]:loc[3]]), children)
+	})
+}
+
+// MentionMatcher returns a matcher of mentions, such as "@name", replacing them with mention
+// nodes. Mentions follow no letter or digit, so that email addresses are not mentions.
+func MentionMatcher() TextMatcher {
+	return submatchMatcher(mentionRegexp, func(text string, loc []int, children []*TextNode) lexical.Node {
+		return withFormat(NewMentionNode(text[loc[4]:loc[5]], text[loc[2]:loc[3]]), children)
+	})
+}
+
+// submatchMatcher returns a matcher of the first submatch of re
+func submatchMatcher(re *regexp.Regexp, node func(text string, loc []int, children []*TextNode) lexical.Node) TextMatcher {
+	return func(text string) []MatcherResult {
+		var results []MatcherResult
+		for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+			results = append(results, MatcherResult{
+				Start: loc[2],
+				End:   loc[3],
+				Node: func(children []*TextNode) lexical.Node {
+					return node(text, loc, children)
+				},
+			})
+		}
+
+		return results
+	}
+}
+
+// withFormat sets the format and style of the text node of node to those of the first child
+func withFormat[T interface{ text() *TextNode }](node T, children []*TextNode) T {
+	if len(children) > 0 {
+		node.text().Format = children[0].Format
+		node.text().Style = children[0].Style
+	}
+
+	return node
+}
+
+// AutoLinkText replaces the matches of matchers in the text of root with the nodes of the
+// matches, and returns the number of nodes created. Matches can span adjacent text nodes of
+// different formats. Of overlapping matches the first starting is kept, or the one of the
+// first matcher. Text in links, including autolinks unlinked by the user, text in code, text
+// nodes not in normal mode and text entities, such as hashtags and mentions, are left
+// unchanged. Text around matches keeps the type of its node.
+func AutoLinkText(root *RootNode, matchers ...TextMatcher) int {
+	created := 0
+	Walk(root, func(node lexical.Node, path Path) bool {
+		if _, ok := node.(interface{ link() *LinkNode }); ok {
+			return false
+		}
+
+		parent, ok := node.(Parent)
+		if !ok || nodeType(node) == "code" {
+			return false
+		}
+
+		children := parent.ChildNodes()
+		runs := textRuns(*children, plainText)
+		for i := len(runs) - 1; i >= 0; i-- {
+			run := runs[i]
+			nodes, n := run.match(matchers)
+			if n == 0 {
+				continue
+			}
+
+			created += n
+			*children = slices.Replace(*children, run.index, run.index+len(run.nodes), nodes...)
+		}
+
+		return true
+	})
+
+	return created
+}
+
+// plainText reports whether node is text in normal mode outside inline code and not a text
+// entity
+func plainText(node lexical.Node, tn *TextNode) bool {
+	_, entity := node.(interface{ textEntity() })
+	return !entity && simpleText(tn) && tn.Format&FormatCode == 0
+}
+
+// match returns the nodes of the run with the matches of matchers replaced and the number
+// of replacements
+func (r textRunNodes) match(matchers []TextMatcher) (lexical.NodeArray, int) {
+	var found []MatcherResult
+	for _, matcher := range matchers {
+		found = append(found, matcher(r.text)...)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Start < found[j].Start
+	})
+
+	var nodes lexical.NodeArray
+	n, offset := 0, 0
+	for _, m := range found {
+		if m.Start < offset || m.Start >= m.End {
+			continue
+		}
+
+		nodes = append(nodes, r.parts(offset, m.Start)...)
+		nodes = append(nodes, m.Node(r.slice(m.Start, m.End)))
+		offset = m.End
+		n++
+	}
+
+	if n == 0 {
+		return nil, 0
+	}
+
+	return append(nodes, r.parts(offset, len(r.text))...), n
+}
+
+// slice returns copies of the text nodes of the run holding the run text between byte
+// offsets start and end
+func (r textRunNodes) slice(start, end int) []*TextNode {
+	var nodes []*TextNode
+	offset := 0
+	for _, tn := range r.nodes {
+		lo, hi := max(start-offset, 0), min(end-offset, len(tn.Text))
+		if lo < hi {
+			nodes = append(nodes, withText(tn, tn.Text[lo:hi]))
+		}
+		offset += len(tn.Text)
+	}
+
+	return nodes
+}
+
+func appendText(nodes lexical.NodeArray, text []*TextNode) lexical.NodeArray {
+	for _, tn := range text {
+		nodes = append(nodes, tn)
+	}
+
+	return nodes
+}
+
+// parts returns copies of the nodes of the run holding the run text between byte offsets
+// start and end, of the type of the nodes
+func (r textRunNodes) parts(start, end int) lexical.NodeArray {
+	var nodes lexical.NodeArray
+	offset := 0
+	for i, tn := range r.nodes {
+		lo, hi := max(start-offset, 0), min(end-offset, len(tn.Text))
+		if lo < hi {
+			nodes = append(nodes, textPart(r.owners[i], tn, tn.Text[lo:hi]))
+		}
+		offset += len(tn.Text)
+	}
+
+	return nodes
+}
@@ -0,0 +1,72 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+var _ lexical.Node = &MentionNode{}
+
+// MentionNode implements the mention node type of the lexical playground
+type MentionNode struct {
+	TextNode
+	MentionName string `json:"mentionName"`
+}
+
+// NewMentionNode returns a mention node of name holding text, such as "@name"
+func NewMentionNode(name, text string) *MentionNode {
+	return &MentionNode{
+		TextNode: TextNode{
+			BaseNode: BaseNode{NodeType: "mention", Version: 1},
+			Mode:     "segmented",
+			Text:     text,
+		},
+		MentionName: name,
+	}
+}
+
+// Find saves mention node to nodes if mention type is in map
+func (mn *MentionNode) Find(nodes map[string][]lexical.Node) {
+	Find(mn, nodes)
+}
+
+// Type returns type of mention node
+func (mn MentionNode) Type() (string, reflect.Type) {
+	return "mention", reflect.TypeOf(mn)
+}
+
+// textEntity marks mention nodes as text entities, whose text is not edited with the text
+// around them
+func (mn *MentionNode) textEntity() {}
+
+// JSONType returns the type of mention node, which is decoded from JSON directly
+func (mn MentionNode) JSONType() reflect.Type {
+	return reflect.TypeOf(mn)
+}
+
+// Unmarshal unmarshals the mention node
+func (mn *MentionNode) Unmarshal(data map[string]interface{}) error {
+	mnB, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(mnB, mn)
+}
+
+// Valid verifies the mention node is a valid text node with a mention name
+func (mn *MentionNode) Valid() error {
+	err := mn.TextNode.Valid()
+	if err != nil {
+		return err
+	}
+
+	if mn.MentionName == "" {
+		return fmt.Errorf("%s: invalid mention node: mention name is empty", pkg)
+	}
+
+	return nil
+}
@@ -0,0 +1,295 @@
+package nodes
+
+import (
+	"strings"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// Conflict is a region of a merge where ours and theirs changed the same content differently.
+// The merged document holds the ours version of the region, starting at Path.
+type Conflict struct {
+	Path   Path
+	Base   lexical.NodeArray
+	Ours   lexical.NodeArray
+	Theirs lexical.NodeArray
+}
+
+// Merge merges the changes made from base to ours and from base to theirs.
+//
+// Changes to different blocks, and changes to different words of the same text
+// node, are merged automatically. Overlapping changes are returned as conflicts
+// and the merged document keeps the ours version of each conflict region. Overlapping
+// changes of the properties of the root element are returned as a conflict at the root
+// path, holding the root elements without their children.
+func Merge(base, ours, theirs *RootNode) (*RootNode, []Conflict) {
+	var conflicts []Conflict
+	node, ok := mergeShells(shell(&base.Root), shell(&ours.Root), shell(&theirs.Root))
+	if !ok {
+		node = shell(&ours.Root)
+		conflicts = append(conflicts, Conflict{
+			Path:   Path{},
+			Base:   lexical.NodeArray{shell(&base.Root)},
+			Ours:   lexical.NodeArray{shell(&ours.Root)},
+			Theirs: lexical.NodeArray{shell(&theirs.Root)},
+		})
+	}
+
+	root, _ := node.(*ElementNode)
+
+	children, nested := mergeNodes(base.Root.Children, ours.Root.Children, theirs.Root.Children, Path{})
+	root.Children = children
+	return &RootNode{Root: *root}, append(conflicts, nested...)
+}
+
+type fateKind int
+
+const (
+	fateKeep fateKind = iota
+	fateModify
+	fateDelete
+)
+
+// fate is what one side did to a node of the base
+type fate struct {
+	kind fateKind
+	node lexical.Node
+}
+
+// changes returns the fate of every base node and the nodes inserted before each base node.
+// Nodes inserted after the last base node are in inserts[len(base)].
+func changes(base, side lexical.NodeArray) ([]fate, []lexical.NodeArray) {
+	fates := make([]fate, len(base))
+	inserts := make([]lexical.NodeArray, len(base)+1)
+	for _, op := range alignNodes(base, side, false) {
+		switch op.kind {
+		case nodeInsert:
+			inserts[op.a] = append(inserts[op.a], side[op.b])
+		case nodeDelete:
+			fates[op.a] = fate{kind: fateDelete}
+		case nodeModify:
+			fates[op.a] = fate{kind: fateModify, node: side[op.b]}
+		}
+	}
+
+	return fates, inserts
+}
+
+func mergeNodes(base, ours, theirs lexical.NodeArray, path Path) (lexical.NodeArray, []Conflict) {
+	oursFates, oursInserts := changes(base, ours)
+	theirsFates, theirsInserts := changes(base, theirs)
+
+	var merged lexical.NodeArray
+	var conflicts []Conflict
+	conflict := func(b, o, t lexical.NodeArray) {
+		conflicts = append(conflicts, Conflict{Path: path.Child(len(merged)), Base: b, Ours: o, Theirs: t})
+		merged = append(merged, cloneNodes(o)...)
+	}
+
+	for i := 0; i <= len(base); i++ {
+		o, t := oursInserts[i], theirsInserts[i]
+		switch {
+		case len(t) == 0 || equalNodes(o, t):
+			merged = append(merged, cloneNodes(o)...)
+		case len(o) == 0:
+			merged = append(merged, cloneNodes(t)...)
+		default:
+			conflict(nil, o, t)
+		}
+
+		if i == len(base) {
+			break
+		}
+
+		of, tf := oursFates[i], theirsFates[i]
+		switch {
+		case of.kind == fateKeep && tf.kind == fateKeep:
+			merged = append(merged, Clone(base[i]))
+		case tf.kind == fateKeep:
+			merged = append(merged, fateNodes(of)...)
+		case of.kind == fateKeep:
+			merged = append(merged, fateNodes(tf)...)
+		case of.kind == fateDelete && tf.kind == fateDelete:
+		case of.kind == fateModify && tf.kind == fateModify:
+			node, nested, ok := mergeNode(base[i], of.node, tf.node, path.Child(len(merged)))
+			if !ok {
+				conflict(lexical.NodeArray{base[i]}, lexical.NodeArray{of.node}, lexical.NodeArray{tf.node})
+				continue
+			}
+
+			merged = append(merged, node)
+			conflicts = append(conflicts, nested...)
+		default:
+			conflict(lexical.NodeArray{base[i]}, fateNodes(of), fateNodes(tf))
+		}
+	}
+
+	return merged, conflicts
+}
+
+// mergeNode merges two modifications of the same base node. ok is false when the
+// modifications overlap outside of the node's children.
+func mergeNode(base, ours, theirs lexical.Node, path Path) (lexical.Node, []Conflict, bool) {
+	if Equal(ours, theirs) {
+		return Clone(ours), nil, true
+	}
+
+	if nodeType(base) != nodeType(ours) || nodeType(base) != nodeType(theirs) {
+		return nil, nil, false
+	}
+
+	baseText, baseIsText := TextNodeOf(base)
+	oursText, oursIsText := TextNodeOf(ours)
+	theirsText, theirsIsText := TextNodeOf(theirs)
+	if baseIsText && oursIsText && theirsIsText {
+		text, ok := mergeText(baseText.Text, oursText.Text, theirsText.Text)
+		if !ok {
+			return nil, nil, false
+		}
+
+		node, ok := mergeShells(withText(base, ""), withText(ours, ""), withText(theirs, ""))
+		if !ok {
+			return nil, nil, false
+		}
+
+		return withText(node, text), nil, true
+	}
+
+	baseParent, baseIsParent := base.(Parent)
+	oursParent, oursIsParent := ours.(Parent)
+	theirsParent, theirsIsParent := theirs.(Parent)
+	if !baseIsParent || !oursIsParent || !theirsIsParent {
+		return nil, nil, false
+	}
+
+	node, ok := mergeShells(shell(baseParent), shell(oursParent), shell(theirsParent))
+	if !ok {
+		return nil, nil, false
+	}
+
+	parent, _ := node.(Parent)
+	children, conflicts := mergeNodes(*baseParent.ChildNodes(), *oursParent.ChildNodes(), *theirsParent.ChildNodes(), path)
+	*parent.ChildNodes() = children
+	return parent, conflicts, true
+}
+
+// mergeShells returns the side that changed the node's own properties
+func mergeShells(base, ours, theirs lexical.Node) (lexical.Node, bool) {
+	switch {
+	case Equal(base, ours):
+		return theirs, true
+	case Equal(base, theirs), Equal(ours, theirs):
+		return ours, true
+	}
+
+	return nil, false
+}
+
+func fateNodes(f fate) lexical.NodeArray {
+	if f.kind == fateDelete {
+		return nil
+	}
+
+	return lexical.NodeArray{Clone(f.node)}
+}
+
+func cloneNodes(nodes lexical.NodeArray) lexical.NodeArray {
+	var clones lexical.NodeArray
+	for _, node := range nodes {
+		clones = append(clones, Clone(node))
+	}
+
+	return clones
+}
+
+func equalNodes(a, b lexical.NodeArray) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hunk replaces the base tokens in [start, end) with tokens
+type hunk struct {
+	start, end int
+	tokens     []string
+}
+
+func textHunks(base, side []string) []hunk {
+	var hunks []hunk
+	var current *hunk
+	for _, op := range diffSequence(len(base), len(side), func(i, j int) bool {
+		return base[i] == side[j]
+	}) {
+		if op.kind == nodeEqual {
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			continue
+		}
+
+		if current == nil {
+			current = &hunk{start: op.a, end: op.a}
+		}
+
+		switch op.kind {
+		case nodeDelete:
+			current.end = op.a + 1
+		case nodeInsert:
+			current.tokens = append(current.tokens, side[op.b])
+		}
+	}
+
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+
+	return hunks
+}
+
+// mergeText merges word level changes to a text. ok is false when the changes overlap.
+func mergeText(base, ours, theirs string) (string, bool) {
+	tokens := tokenize(base)
+	oursHunks := textHunks(tokens, tokenize(ours))
+	theirsHunks := textHunks(tokens, tokenize(theirs))
+
+	var b strings.Builder
+	i := 0
+	for len(oursHunks) > 0 || len(theirsHunks) > 0 {
+		var next hunk
+		switch {
+		case len(theirsHunks) == 0:
+			next, oursHunks = oursHunks[0], oursHunks[1:]
+		case len(oursHunks) == 0:
+			next, theirsHunks = theirsHunks[0], theirsHunks[1:]
+		default:
+			o, t := oursHunks[0], theirsHunks[0]
+			overlap := o.start == t.start || (o.start < t.end && t.start < o.end)
+			switch {
+			case overlap && o.end == t.end && strings.Join(o.tokens, "") == strings.Join(t.tokens, ""):
+				next, oursHunks, theirsHunks = o, oursHunks[1:], theirsHunks[1:]
+			case overlap:
+				return "", false
+			case o.start < t.start:
+				next, oursHunks = o, oursHunks[1:]
+			default:
+				next, theirsHunks = t, theirsHunks[1:]
+			}
+		}
+
+		b.WriteString(strings.Join(tokens[i:next.start], ""))
+		b.WriteString(strings.Join(next.tokens, ""))
+		i = next.end
+	}
+
+	b.WriteString(strings.Join(tokens[i:], ""))
+	return b.String(), true
+}
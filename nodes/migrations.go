@@ -0,0 +1,49 @@
+package nodes
+
+import "github.com/tylertravisty/go-lexical"
+
+// RegisterMigrations registers the upgrades of older serialized forms of Lexical node types:
+// paragraphs written before Lexical added textFormat and textStyle take them from their first
+// text node, and list items written without checked are unchecked. The upgrades apply to the
+// serialized nodes, so they also upgrade list items of node types registered by the application.
+func RegisterMigrations() error {
+	for _, field := range []string{"textFormat", "textStyle"} {
+		err := lexical.RegisterUpgrade("paragraph", field, upgradeParagraphText)
+		if err != nil {
+			return err
+		}
+	}
+
+	return lexical.RegisterUpgrade("listitem", "checked", upgradeListItemChecked)
+}
+
+// upgradeParagraphText sets the missing text format and style of a serialized paragraph to
+// those of its first text node, as Lexical does when the selection enters the paragraph
+func upgradeParagraphText(data map[string]any) error {
+	format, style := any(0), any("")
+	children, _ := data["children"].([]any)
+	for _, child := range children {
+		if c, ok := child.(map[string]any); ok && c["type"] == "text" {
+			format, style = c["format"], c["style"]
+			break
+		}
+	}
+
+	if _, exists := data["textFormat"]; !exists && format != nil {
+		data["textFormat"] = format
+	}
+
+	if _, exists := data["textStyle"]; !exists && style != nil {
+		data["textStyle"] = style
+	}
+
+	return nil
+}
+
+// upgradeListItemChecked sets the missing checked state of a serialized list item to false,
+// the state Lexical gives check list items without one. Lexical ignores it on items of other
+// list types.
+func upgradeListItemChecked(data map[string]any) error {
+	data["checked"] = false
+	return nil
+}
@@ -2,7 +2,13 @@ package nodes
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/tylertravisty/go-lexical"
@@ -23,6 +29,7 @@ func TestUnmarshal(t *testing.T) {
 	tests := []string{
 		`{"root":{"children":[{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"asdf","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}],"direction":"ltr","format":"","indent":0,"type":"root","version":1}}`,
 		`{"root":{"children":[{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"with a link? ","type":"text","version":1},{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"www.google.com","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"autolink","version":1,"rel":null,"target":null,"title":null,"url":"https://www.google.com","isUnlinked":false},{"detail":0,"format":0,"mode":"normal","style":"","text":" cool!","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}],"direction":"ltr","format":"","indent":0,"type":"root","version":1}}`,
+		"{\n \"root\": {\n  \"children\": [\n   {\n    \"children\": [{\"text\": \"a \\\"quoted\\\" {text}\", \"\\u0074ype\": \"text\", \"version\": 1}],\n    \"type\" : \"paragraph\",\n    \"version\": 1\n   }\n  ],\n  \"type\": \"root\",\n  \"version\": 1\n }\n}",
 	}
 
 	for _, test := range tests {
@@ -206,3 +213,2172 @@ func testParagraphNodes(t *testing.T) {
 		t.Fatalf("expected textStyle %s; got %s", expectedTextStyle, paragraph.TextStyle)
 	}
 }
+
+func TestClone(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&AutoLinkNode{}, &ParagraphNode{}, &TextNode{})
+	message := `{"root":{"children":[{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"with a link? ","type":"text","version":1},{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"www.google.com","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"autolink","version":1,"rel":null,"target":"_blank","title":null,"url":"https://www.google.com","isUnlinked":false},{"detail":0,"format":0,"mode":"normal","style":"","text":" cool!","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}],"direction":"ltr","format":"","indent":0,"type":"root","version":1}}`
+
+	var root RootNode
+	err := json.Unmarshal([]byte(message), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	clone := root.Clone()
+	if !root.Equal(clone) {
+		t.Fatal("clone is not equal to original")
+	}
+
+	paragraph := clone.Root.Children[0].(*ParagraphNode)
+	autolink := paragraph.Children[1].(*AutoLinkNode)
+	*autolink.Target = "_self"
+	*paragraph.Direction = "rtl"
+	autolink.Children[0].(*TextNode).Text = "changed"
+	paragraph.Children = paragraph.Children[:1]
+
+	original := root.Root.Children[0].(*ParagraphNode)
+	if len(original.Children) != 3 {
+		t.Fatalf("expected length of original paragraph children 3; got %d", len(original.Children))
+	}
+
+	originalLink := original.Children[1].(*AutoLinkNode)
+	if *originalLink.Target != "_blank" {
+		t.Fatalf("expected original target %s; got %s", "_blank", *originalLink.Target)
+	}
+
+	if *original.Direction != "ltr" {
+		t.Fatalf("expected original direction %s; got %s", "ltr", *original.Direction)
+	}
+
+	if text := originalLink.Children[0].(*TextNode).Text; text != "www.google.com" {
+		t.Fatalf("expected original text %s; got %s", "www.google.com", text)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	ltr := "ltr"
+	rtl := "rtl"
+	tests := []struct {
+		a, b     lexical.Node
+		opts     []EqualOption
+		expected bool
+	}{
+		{
+			a:        &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 1}, Text: "a"},
+			b:        &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 1}, Text: "a"},
+			expected: true,
+		},
+		{
+			a:        &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 1}, Text: "a"},
+			b:        &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 1}, Text: "b"},
+			expected: false,
+		},
+		{
+			a:        &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 1}, Text: "a"},
+			b:        &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 2}, Text: "a"},
+			expected: false,
+		},
+		{
+			a:        &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 1}, Text: "a"},
+			b:        &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 2}, Text: "a"},
+			opts:     []EqualOption{IgnoreVersion()},
+			expected: true,
+		},
+		{
+			a:        &TextNode{Text: "a", Format: 1},
+			b:        &TextNode{Text: "a", Format: 2},
+			opts:     []EqualOption{IgnoreFormat()},
+			expected: true,
+		},
+		{
+			a:        &ParagraphNode{ElementNode: ElementNode{Direction: &ltr}},
+			b:        &ParagraphNode{ElementNode: ElementNode{Direction: &rtl}},
+			expected: false,
+		},
+		{
+			a:        &ParagraphNode{ElementNode: ElementNode{Direction: &ltr}},
+			b:        &ParagraphNode{ElementNode: ElementNode{Direction: &rtl}},
+			opts:     []EqualOption{IgnoreDirection()},
+			expected: true,
+		},
+		{
+			a:        &ParagraphNode{ElementNode: ElementNode{Children: lexical.NodeArray{&TextNode{Text: "a"}}}},
+			b:        &ParagraphNode{ElementNode: ElementNode{Children: lexical.NodeArray{&TextNode{Text: "b"}}}},
+			expected: false,
+		},
+		{
+			a:        &ParagraphNode{},
+			b:        &ElementNode{},
+			expected: false,
+		},
+	}
+
+	for i, test := range tests {
+		equal := Equal(test.a, test.b, test.opts...)
+		if equal != test.expected {
+			t.Fatalf("test %d: expected equal %t; got %t", i, test.expected, equal)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&AutoLinkNode{}, &LinkNode{}, &ParagraphNode{}, &TextNode{})
+	old := document(
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"moved block","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"unchanged","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"the quick fox","type":"text","version":1},{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"link","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"link","version":1,"rel":null,"target":null,"title":null,"url":"https://a.com"}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"also unchanged","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"removed","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+	)
+	new := document(
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"unchanged","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":1,"mode":"normal","style":"","text":"the slow fox","type":"text","version":1},{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"link","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"link","version":1,"rel":null,"target":null,"title":null,"url":"https://b.com"}],"direction":"ltr","format":"","indent":1,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"inserted","type":"text","version":1}],"direction":"ltr","format":"center","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"also unchanged","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"moved block","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+	)
+
+	var oldRoot, newRoot RootNode
+	err := json.Unmarshal([]byte(old), &oldRoot)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	err = json.Unmarshal([]byte(new), &newRoot)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	changes := Diff(&oldRoot, &newRoot)
+	expected := []struct {
+		kind    ChangeKind
+		oldPath string
+		path    string
+	}{
+		{kind: FormatChanged, oldPath: "/2", path: "/1"},
+		{kind: TextChanged, oldPath: "/2/0", path: "/1/0"},
+		{kind: FormatChanged, oldPath: "/2/0", path: "/1/0"},
+		{kind: URLChanged, oldPath: "/2/1", path: "/1/1"},
+		{kind: BlockInserted, oldPath: "", path: "/2"},
+		{kind: BlockRemoved, oldPath: "/4", path: ""},
+		{kind: BlockMoved, oldPath: "/0", path: "/4"},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes; got %d: %v", len(expected), len(changes), changes)
+	}
+
+	for i, change := range changes {
+		if change.Kind != expected[i].kind || change.OldPath.String() != expected[i].oldPath || change.Path.String() != expected[i].path {
+			t.Fatalf("change %d: expected %s %s -> %s; got %s %s -> %s", i, expected[i].kind, expected[i].oldPath, expected[i].path, change.Kind, change.OldPath, change.Path)
+		}
+	}
+
+	if root := (Path{}).String(); root != "/" {
+		t.Fatalf("expected root path /; got %q", root)
+	}
+
+	edits := changes[1].Text
+	expectedEdits := []TextEdit{{TextEqual, "the "}, {TextDelete, "quick"}, {TextInsert, "slow"}, {TextEqual, " fox"}}
+	if len(edits) != len(expectedEdits) {
+		t.Fatalf("expected %d text edits; got %d: %v", len(expectedEdits), len(edits), edits)
+	}
+
+	for i, edit := range edits {
+		if edit != expectedEdits[i] {
+			t.Fatalf("text edit %d: expected %v; got %v", i, expectedEdits[i], edit)
+		}
+	}
+
+	link := oldRoot.Root.Children[2].(*ParagraphNode).Children[1]
+	dynamicLink := &DynamicNode{spec: &NodeSpec{Type: "link", Base: BaseElement}, Properties: map[string]any{"url": "https://a.com"}}
+	changes = diffNodes(link, dynamicLink, Path{0}, Path{0})
+	if len(changes) == 0 || changes[0].Kind != NodeChanged {
+		t.Fatalf("expected node changed between link types; got %v", changes)
+	}
+}
+
+func document(blocks ...string) string {
+	return `{"root":{"children":[` + strings.Join(blocks, ",") + `],"direction":"ltr","format":"","indent":0,"type":"root","version":1}}`
+}
+
+func TestRedline(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&DecoratorNode{}, &LinkNode{}, &MarkNode{}, &ParagraphNode{}, &TextNode{})
+	old := document(
+		`{"children":[{"detail":0,"format":1,"mode":"normal","style":"","text":"the quick fox","type":"text","version":1},{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"link","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"link","version":1,"rel":null,"target":null,"title":null,"url":"https://a.com"}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"removed","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"children":[],"direction":null,"format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+	)
+	new := document(
+		`{"children":[{"detail":0,"format":1,"mode":"normal","style":"","text":"the slow fox","type":"text","version":1},{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"link","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"link","version":1,"rel":null,"target":null,"title":null,"url":"javascript:alert(1)"}],"direction":"ltr","format":"","indent":1,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+		`{"type":"decorator","version":1}`,
+	)
+
+	var oldRoot, newRoot RootNode
+	err := json.Unmarshal([]byte(old), &oldRoot)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	err = json.Unmarshal([]byte(new), &newRoot)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	expected := `<p><strong>the </strong><del><strong>quick</strong></del><ins><strong>slow</strong></ins><strong> fox</strong>` +
+		`<del><a href="https://a.com">link</a></del><ins><a href="about:blank">link</a></ins></p>` +
+		`<p><del>removed</del></p><p><del></del></p><p><ins><span data-lexical-decorator="true"></span></ins></p>`
+	redline := RedlineHTML(&oldRoot, &newRoot)
+	if redline != expected {
+		t.Fatalf("expected redline %s; got %s", expected, redline)
+	}
+
+	redlineB, err := json.Marshal(Redline(&oldRoot, &newRoot))
+	if err != nil {
+		t.Fatal("json.Marshal err:", err)
+	}
+
+	var root RootNode
+	err = json.Unmarshal(redlineB, &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	err = root.Valid()
+	if err != nil {
+		t.Fatal("root.Valid err:", err)
+	}
+
+	err = ValidateStructure(&root)
+	if err != nil {
+		t.Fatal("ValidateStructure err:", err)
+	}
+
+	if html := HTML(&root); html != expected {
+		t.Fatalf("expected html %s; got %s", expected, html)
+	}
+
+	urls := map[string]string{
+		"/docs?a=1":               "/docs?a=1",
+		"MAILTO:ann@example.com":  "MAILTO:ann@example.com",
+		"tel:+15551234567":        "tel:+15551234567",
+		"data:text/html,<script>": "about:blank",
+		"JavaScript:alert(1)":     "about:blank",
+		" javascript:alert(1)":    "about:blank",
+		"java\tscript:alert(1)":   "about:blank",
+		"vbscript:msgbox(1)":      "about:blank",
+	}
+	for rawURL, expected := range urls {
+		if sanitized := sanitizeURL(rawURL); sanitized != expected {
+			t.Fatalf("expected url %q sanitized as %q; got %q", rawURL, expected, sanitized)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&ParagraphNode{}, &TextNode{})
+	paragraph := func(text string) string {
+		return `{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"` + text + `","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`
+	}
+	base := document(paragraph("the quick brown fox"), paragraph("hello"), paragraph("bye"))
+	ours := document(paragraph("the slow brown fox"), paragraph("hello world"), paragraph("bye"))
+	theirs := document(paragraph("the quick brown dog"), paragraph("hello there"), paragraph("new"))
+
+	var baseRoot, oursRoot, theirsRoot RootNode
+	for _, doc := range []struct {
+		message string
+		root    *RootNode
+	}{{base, &baseRoot}, {ours, &oursRoot}, {theirs, &theirsRoot}} {
+		err := json.Unmarshal([]byte(doc.message), doc.root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+	}
+
+	merged, conflicts := Merge(&baseRoot, &oursRoot, &theirsRoot)
+	expected := []string{"the slow brown dog", "hello world", "new"}
+	if len(merged.Root.Children) != len(expected) {
+		t.Fatalf("expected length of merged children %d; got %d", len(expected), len(merged.Root.Children))
+	}
+
+	for i, text := range expected {
+		got := merged.Root.Children[i].(*ParagraphNode).Children[0].(*TextNode).Text
+		if got != text {
+			t.Fatalf("expected merged block %d text %s; got %s", i, text, got)
+		}
+	}
+
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict; got %d", len(conflicts))
+	}
+
+	if conflicts[0].Path.String() != "/1/0" {
+		t.Fatalf("expected conflict path %s; got %s", "/1/0", conflicts[0].Path)
+	}
+
+	if text := conflicts[0].Theirs[0].(*TextNode).Text; text != "hello there" {
+		t.Fatalf("expected conflict theirs text %s; got %s", "hello there", text)
+	}
+
+	oursRoot.Root.Format = "left"
+	theirsRoot.Root.Format = "right"
+	merged, conflicts = Merge(&baseRoot, &oursRoot, &theirsRoot)
+	if merged.Root.Format != "left" || len(conflicts) != 2 || conflicts[0].Path.String() != "/" {
+		t.Fatalf("expected ours root format and root conflict; got %q and %v", merged.Root.Format, conflicts)
+	}
+
+	if format := conflicts[0].Theirs[0].(*ElementNode).Format; format != "right" {
+		t.Fatalf("expected conflict theirs root format %s; got %s", "right", format)
+	}
+}
+
+func benchmarkDocument(blocks, depth int) []byte {
+	text := `{"detail":0,"format":0,"mode":"normal","style":"","text":"lorem ipsum dolor sit amet","type":"text","version":1}`
+	inline := text
+	for i := 0; i < depth; i++ {
+		inline = `{"children":[` + text + `,` + inline + `],"direction":"ltr","format":"","indent":0,"type":"mark","version":1,"ids":["a"]}`
+	}
+
+	paragraphs := make([]string, blocks)
+	for i := range paragraphs {
+		paragraphs[i] = `{"children":[` + text + `,` + inline + `],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`
+	}
+
+	return []byte(document(paragraphs...))
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&MarkNode{}, &ParagraphNode{}, &TextNode{})
+	message := benchmarkDocument(200, 8)
+
+	b.SetBytes(int64(len(message)))
+	b.ReportAllocs()
+	for b.Loop() {
+		var root RootNode
+		err := json.Unmarshal(message, &root)
+		if err != nil {
+			b.Fatal("json.Unmarshal err:", err)
+		}
+	}
+}
+
+func TestUnmarshalNodeKeys(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&ParagraphNode{}, &TextNode{})
+
+	tests := []string{
+		`{"children":[{"text":"a","type":"text","version":1}],"type":"paragraph","version":1}`,
+		`{"Children":[{"text":"a","type":"text","version":1}],"TYPE":"paragraph","version":1}`,
+		`{"\u0063hildren":[{"text":"a","type":"text","version":1}],"\u0074ype":"paragraph","version":1}`,
+		`{"children":[],"children":[{"text":"a","type":"text","version":1}],"type":"paragraph","version":1}`,
+	}
+
+	for _, test := range tests {
+		node, err := lexical.UnmarshalNode([]byte(test))
+		if err != nil {
+			t.Fatalf("UnmarshalNode(%s) err: %v", test, err)
+		}
+
+		p, ok := node.(*ParagraphNode)
+		if !ok || len(p.Children) != 1 {
+			t.Fatalf("expected paragraph with 1 child from %s; got %#v", test, node)
+		}
+	}
+
+	node, err := lexical.UnmarshalNode([]byte(`{"children":[{"text":"a","type":"text","version":1}],"text":"b","type":"text","version":1}`))
+	if err != nil {
+		t.Fatal("UnmarshalNode err:", err)
+	}
+
+	if tn, ok := node.(*TextNode); !ok || tn.Text != "b" {
+		t.Fatalf("expected text node b; got %#v", node)
+	}
+
+	for _, test := range []string{`{"type":"paragraph","children":[}`, `{"type":"paragraph",}`, `{"type":"paragraph"} x`} {
+		if _, err := lexical.UnmarshalNode([]byte(test)); err == nil {
+			t.Fatalf("UnmarshalNode(%s) err is nil; expected non-nil err", test)
+		}
+	}
+}
+
+type upperTextNode struct {
+	TextNode
+}
+
+func (utn upperTextNode) Type() (string, reflect.Type) {
+	return "upper-text", reflect.TypeOf(utn)
+}
+
+func (utn *upperTextNode) Unmarshal(data map[string]any) error {
+	err := utn.TextNode.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+
+	utn.Text = strings.ToUpper(utn.Text)
+	return nil
+}
+
+type tagsNode struct {
+	DecoratorNode
+	Children []string `json:"children"`
+}
+
+func (tn tagsNode) Type() (string, reflect.Type) {
+	return "tags", reflect.TypeOf(tn)
+}
+
+func (tn tagsNode) JSONType() reflect.Type {
+	return reflect.TypeOf(tn)
+}
+
+func TestUnmarshalCustomNodes(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&ParagraphNode{}, &tagsNode{}, &upperTextNode{})
+
+	upper := `{"text":"a","type":"upper-text","version":1}`
+	tags := `{"children":["x","y"],"type":"tags","version":1}`
+	var root RootNode
+	err := json.Unmarshal([]byte(document(`{"children":[`+upper+`,`+tags+`],"type":"paragraph","version":1}`)), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	children := root.Root.Children[0].(*ParagraphNode).Children
+	if utn, ok := children[0].(*upperTextNode); !ok || utn.Text != "A" {
+		t.Fatalf("expected upper text node A; got %#v", children[0])
+	}
+
+	if tn, ok := children[1].(*tagsNode); !ok || !reflect.DeepEqual(tn.Children, []string{"x", "y"}) {
+		t.Fatalf("expected tags node [x y]; got %#v", children[1])
+	}
+
+	_, err = lexical.UnmarshalNode([]byte(`{"children":["x"],"type":"paragraph","version":1}`))
+	if err == nil {
+		t.Fatal("UnmarshalNode err is nil; expected non-nil err")
+	}
+
+	na := lexical.NodeArray{}
+	err = na.UnmarshalJSON([]byte("null"))
+	if err != nil {
+		t.Fatal("NodeArray.UnmarshalJSON err:", err)
+	}
+
+	if na != nil {
+		t.Fatalf("expected nil node array; got %#v", na)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&MarkNode{}, &ParagraphNode{}, &TextNode{})
+	text := `{"detail":0,"format":0,"mode":"normal","style":"","text":"asdf","type":"text","version":1}`
+	paragraph := `{"children":[` + text + `],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`
+
+	root, err := Decode(strings.NewReader(document(paragraph)), lexical.DefaultLimits)
+	if err != nil {
+		t.Fatal("Decode err:", err)
+	}
+
+	if len(root.Root.Children) != 1 {
+		t.Fatalf("expected length of root children 1; got %d", len(root.Root.Children))
+	}
+}
+
+func TestDecodeReturnsError(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&MarkNode{}, &ParagraphNode{}, &TextNode{})
+	text := `{"detail":0,"format":0,"mode":"normal","style":"","text":"asdf","type":"text","version":1}`
+	paragraph := `{"children":[` + text + `],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`
+
+	deep := strings.Repeat(`{"type":"mark","children":[`, 100000) + strings.Repeat(`]}`, 100000)
+	tests := []struct {
+		limit   string
+		limits  lexical.Limits
+		message string
+	}{
+		{limit: "MaxBytes", limits: lexical.Limits{MaxBytes: 100}, message: document(paragraph)},
+		{limit: "MaxDepth", limits: lexical.Limits{MaxDepth: 64}, message: document(deep)},
+		{limit: "MaxNodes", limits: lexical.Limits{MaxNodes: 3}, message: document(paragraph, paragraph)},
+		{limit: "MaxChildren", limits: lexical.Limits{MaxChildren: 2}, message: document(paragraph, paragraph, paragraph)},
+		{limit: "MaxTextLength", limits: lexical.Limits{MaxTextLength: 3}, message: document(paragraph)},
+		{limit: "MaxDepth", limits: lexical.Limits{MaxDepth: 64}, message: document(strings.ReplaceAll(deep, `"children"`, `"Children"`))},
+		{limit: "MaxDepth", limits: lexical.Limits{MaxDepth: 64}, message: document(strings.ReplaceAll(deep, `"children"`, `"\u0063hildren"`))},
+		{limit: "MaxNodes", limits: lexical.Limits{MaxNodes: 3}, message: strings.ReplaceAll(document(paragraph, paragraph), `"children"`, `"\u0063hildren"`)},
+		{limit: "MaxChildren", limits: lexical.Limits{MaxChildren: 2}, message: strings.ReplaceAll(document(paragraph, paragraph, paragraph), `"children"`, `"CHILDREN"`)},
+		{limit: "MaxTextLength", limits: lexical.Limits{MaxTextLength: 3}, message: strings.Replace(document(paragraph), `"text":`, `"Text":`, 1)},
+		{limit: "MaxTextLength", limits: lexical.Limits{MaxTextLength: 3}, message: strings.Replace(document(paragraph), `"text":`, `"t\u0065xt":`, 1)},
+	}
+
+	for _, test := range tests {
+		_, err := Decode(strings.NewReader(test.message), test.limits)
+		var limitErr *lexical.LimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("expected limit error; got %v", err)
+		}
+
+		if limitErr.Limit != test.limit {
+			t.Fatalf("expected limit %s; got %s", test.limit, limitErr.Limit)
+		}
+	}
+
+	var root RootNode
+	err := json.Unmarshal([]byte(document(strings.Repeat(`{"type":"mark","children":[`, 100)+strings.Repeat(`]}`, 100))), &root)
+	var limitErr *lexical.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxDepth" {
+		t.Fatalf("expected MaxDepth limit error; got %v", err)
+	}
+}
+
+type versionedNode struct {
+	TextNode
+}
+
+func (vn *versionedNode) CurrentVersion() int {
+	return 2
+}
+
+func (vn versionedNode) Type() (string, reflect.Type) {
+	return "versioned", reflect.TypeOf(vn)
+}
+
+func TestMigrations(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.ResetMigrations()
+	lexical.RegisterNodes(&ParagraphNode{}, &versionedNode{})
+	lexical.RegisterMigration("versioned", 1, func(data map[string]any) error {
+		data["text"] = data["content"]
+		delete(data, "content")
+		return nil
+	})
+
+	tests := []string{
+		`{"content":"old","type":"versioned","version":1}`,
+		`{"text":"old","type":"versioned","version":2}`,
+		`{"text":"old","type":"versioned","version":3}`,
+	}
+
+	for _, test := range tests {
+		var root RootNode
+		err := json.Unmarshal([]byte(document(`{"children":[`+test+`],"type":"paragraph","version":1}`)), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		node := root.Root.Children[0].(*ParagraphNode).Children[0].(*versionedNode)
+		if node.Text != "old" {
+			t.Fatalf("expected text %s; got %s", "old", node.Text)
+		}
+	}
+
+	var data map[string]any
+	json.Unmarshal([]byte(tests[0]), &data)
+	node, err := lexical.Unmarshal(data)
+	if err != nil {
+		t.Fatal("lexical.Unmarshal err:", err)
+	}
+
+	if text := node.(*versionedNode).Text; text != "old" {
+		t.Fatalf("expected text %s; got %s", "old", text)
+	}
+
+	if node.(*versionedNode).Version != 2 {
+		t.Fatalf("expected version %d; got %d", 2, node.(*versionedNode).Version)
+	}
+}
+
+func TestRegisterMigrations(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.ResetMigrations()
+	lexical.RegisterNodes(&ParagraphNode{}, &TextNode{})
+	err := RegisterMigrations()
+	if err != nil {
+		t.Fatal("RegisterMigrations err:", err)
+	}
+	defer lexical.ResetMigrations()
+
+	text := `{"detail":0,"format":3,"mode":"normal","style":"color: red","text":"old","type":"text","version":1}`
+	old := document(
+		`{"children":[`+text+`],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1}`,
+		`{"children":[`+text+`],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":1}`,
+		`{"children":[`+text+`],"direction":"ltr","format":"","indent":0,"type":"paragraph","version":1,"textFormat":0,"textStyle":""}`,
+	)
+
+	var root RootNode
+	err = json.Unmarshal([]byte(old), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	expected := []struct {
+		format int
+		style  string
+	}{{3, "color: red"}, {1, "color: red"}, {0, ""}}
+	for i, child := range root.Root.Children {
+		p := child.(*ParagraphNode)
+		if p.TextFormat != expected[i].format || p.TextStyle != expected[i].style {
+			t.Fatalf("paragraph %d: expected text format %d and style %q; got %d and %q", i, expected[i].format, expected[i].style, p.TextFormat, p.TextStyle)
+		}
+
+		if len(p.Children) != 1 {
+			t.Fatalf("paragraph %d: expected length of children 1; got %d", i, len(p.Children))
+		}
+	}
+
+	var data map[string]any
+	json.Unmarshal([]byte(`{"children":[`+text+`],"type":"paragraph","version":1}`), &data)
+	node, err := lexical.Unmarshal(data)
+	if err != nil {
+		t.Fatal("lexical.Unmarshal err:", err)
+	}
+
+	if p := node.(*ParagraphNode); p.TextFormat != 3 || p.TextStyle != "color: red" {
+		t.Fatalf("expected text format 3 and style color: red; got %d and %q", p.TextFormat, p.TextStyle)
+	}
+
+	if err := RegisterMigrations(); err == nil {
+		t.Fatal("RegisterMigrations err is nil; expected non-nil err")
+	}
+
+	lexical.RegisterNodes(&listItemNode{})
+	for _, item := range []string{
+		`{"children":[],"type":"listitem","value":1,"version":1}`,
+		`{"checked":true,"children":[],"type":"listitem","value":1,"version":1}`,
+	} {
+		var root RootNode
+		err = json.Unmarshal([]byte(document(item)), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		var data map[string]any
+		json.Unmarshal([]byte(item), &data)
+		node, err := lexical.Unmarshal(data)
+		if err != nil {
+			t.Fatal("lexical.Unmarshal err:", err)
+		}
+
+		_, expected := data["checked"]
+		for _, li := range []*listItemNode{root.Root.Children[0].(*listItemNode), node.(*listItemNode)} {
+			if li.Checked == nil || *li.Checked != expected {
+				t.Fatalf("expected list item checked %t; got %v", expected, li.Checked)
+			}
+		}
+	}
+}
+
+type listItemNode struct {
+	ElementNode
+	Checked *bool `json:"checked"`
+	Value   int   `json:"value"`
+}
+
+func (lin listItemNode) Type() (string, reflect.Type) {
+	return "listitem", reflect.TypeOf(lin)
+}
+
+func (lin listItemNode) JSONType() reflect.Type {
+	return reflect.TypeOf(lin)
+}
+
+func (lin *listItemNode) Unmarshal(data map[string]any) error {
+	linB, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(linB, lin)
+}
+
+func TestMigrationsReturnError(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.ResetMigrations()
+	lexical.RegisterNodes(&ParagraphNode{}, &versionedNode{})
+	lexical.DefaultMigrations.RejectNewer(true)
+
+	tests := []string{
+		`{"content":"old","type":"versioned","version":1}`,
+		`{"text":"new","type":"versioned","version":3}`,
+	}
+
+	for _, test := range tests {
+		var root RootNode
+		err := json.Unmarshal([]byte(document(`{"children":[`+test+`],"type":"paragraph","version":1}`)), &root)
+		if err == nil {
+			t.Fatal("json.Unmarshal err is nil; expected non-nil err")
+		}
+	}
+
+	// Nodes of an older version without a migration are rejected by both decode paths,
+	// whether newer versions are rejected or not
+	for _, rejectNewer := range []bool{true, false} {
+		lexical.DefaultMigrations.RejectNewer(rejectNewer)
+
+		var root RootNode
+		err := json.Unmarshal([]byte(document(`{"children":[`+tests[0]+`],"type":"paragraph","version":1}`)), &root)
+		if err == nil {
+			t.Fatal("json.Unmarshal err is nil; expected non-nil err")
+		}
+
+		var data map[string]any
+		json.Unmarshal([]byte(tests[0]), &data)
+		_, err = lexical.Unmarshal(data)
+		if err == nil {
+			t.Fatal("lexical.Unmarshal err is nil; expected non-nil err")
+		}
+	}
+
+	var wg sync.WaitGroup
+	var registered atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if lexical.RegisterMigration("versioned", 1, func(map[string]any) error { return nil }) == nil {
+				registered.Add(1)
+			}
+
+			if lexical.RegisterUpgrade("versioned", "text", func(map[string]any) error { return nil }) == nil {
+				registered.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if registered.Load() != 2 {
+		t.Fatalf("expected 2 registrations; got %d", registered.Load())
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&AutoLinkNode{}, &ParagraphNode{}, &TextNode{})
+
+	schema := lexical.DefaultNodeTypes.JSONSchemaFor(reflect.TypeOf(RootNode{}))
+	if schema["$schema"] != lexical.SchemaDraft {
+		t.Fatalf("expected $schema %s; got %v", lexical.SchemaDraft, schema["$schema"])
+	}
+
+	schemaB, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal("json.Marshal err:", err)
+	}
+
+	var decoded struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			OneOf      []map[string]string `json:"oneOf"`
+			Properties map[string]struct {
+				Const string `json:"const"`
+				Enum  []any  `json:"enum"`
+				Items struct {
+					Ref string `json:"$ref"`
+				} `json:"items"`
+			} `json:"properties"`
+		} `json:"$defs"`
+	}
+	err = json.Unmarshal(schemaB, &decoded)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	if _, exists := decoded.Properties["root"]; !exists {
+		t.Fatal("expected root property in document schema")
+	}
+
+	if len(decoded.Defs["node"].OneOf) != 3 {
+		t.Fatalf("expected 3 node types; got %d", len(decoded.Defs["node"].OneOf))
+	}
+
+	paragraph := decoded.Defs["node:paragraph"]
+	if paragraph.Properties["type"].Const != "paragraph" {
+		t.Fatalf("expected type const %s; got %s", "paragraph", paragraph.Properties["type"].Const)
+	}
+
+	if len(paragraph.Properties["format"].Enum) != len(elementFormats) {
+		t.Fatalf("expected %d formats; got %d", len(elementFormats), len(paragraph.Properties["format"].Enum))
+	}
+
+	if len(paragraph.Properties["direction"].Enum) != len(elementDirections)+1 {
+		t.Fatalf("expected %d directions; got %d", len(elementDirections)+1, len(paragraph.Properties["direction"].Enum))
+	}
+
+	if ref := paragraph.Properties["children"].Items.Ref; ref != "#/$defs/node" {
+		t.Fatalf("expected children items ref %s; got %s", "#/$defs/node", ref)
+	}
+
+	if _, exists := decoded.Defs["node:autolink"].Properties["isUnlinked"]; !exists {
+		t.Fatal("expected isUnlinked property in autolink schema")
+	}
+}
+
+func TestDynamicNodes(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&ParagraphNode{}, &TextNode{})
+
+	specs := `[
+		{"type": "callout", "base": "element", "fields": [{"name": "tone", "type": "string", "required": true}], "children": ["paragraph"]},
+		{"type": "equation", "base": "decorator", "fields": [{"name": "equation", "type": "string", "required": true}, {"name": "inline", "type": "boolean"}]}
+	]`
+	err := RegisterSpecs([]byte(specs))
+	if err != nil {
+		t.Fatal("RegisterSpecs err:", err)
+	}
+
+	data := `{"root":{"children":[` +
+		`{"children":[{"children":[{"detail":0,"format":0,"mode":"normal","style":"","text":"Note","type":"text","version":1}],"direction":null,"format":"","indent":0,"textFormat":0,"textStyle":"","type":"paragraph","version":1}],"direction":null,"format":"","indent":0,"tone":"info","type":"callout","version":1},` +
+		`{"equation":"x^2","inline":false,"size":1.50,"type":"equation","version":1}` +
+		`],"direction":null,"format":"","indent":0,"type":"root","version":1}}`
+
+	var root RootNode
+	err = json.Unmarshal([]byte(data), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	callout, ok := root.Root.Children[0].(*DynamicNode)
+	if !ok {
+		t.Fatal("root child is not a dynamic node")
+	}
+
+	if callout.Spec().Type != "callout" {
+		t.Fatalf("expected type %s; got %s", "callout", callout.Spec().Type)
+	}
+
+	if callout.TextContentSize() != 4 {
+		t.Fatalf("expected text content size 4; got %d", callout.TextContentSize())
+	}
+
+	err = root.Root.Valid()
+	if err != nil {
+		t.Fatal("Valid err:", err)
+	}
+
+	found := map[string][]lexical.Node{"text": nil, "equation": nil}
+	root.Find(found)
+	if len(found["text"]) != 1 || len(found["equation"]) != 1 {
+		t.Fatal("expected to find one text node and one equation node")
+	}
+
+	equation := `{"equation":"x^2","inline":false,"size":1.50,"type":"equation","version":1}`
+	equationB, err := json.Marshal(root.Root.Children[1])
+	if err != nil {
+		t.Fatal("json.Marshal err:", err)
+	}
+
+	if string(equationB) != equation {
+		t.Fatalf("expected round trip %s; got %s", equation, equationB)
+	}
+
+	clone := root.Clone()
+	clone.Root.Children[0].(*DynamicNode).Properties["tone"] = "warning"
+	if callout.Properties["tone"] != "info" {
+		t.Fatal("expected clone not to share properties")
+	}
+
+	callout.Properties["tone"] = 1
+	if callout.Valid() == nil {
+		t.Fatal("Valid err is nil for field of wrong type; expected non-nil err")
+	}
+
+	callout.Properties["tone"] = "info"
+	callout.Children = append(callout.Children, &TextNode{})
+	if callout.Valid() == nil {
+		t.Fatal("Valid err is nil for disallowed child; expected non-nil err")
+	}
+
+	schema := lexical.DefaultNodeTypes.JSONSchema()
+	defs, _ := schema["$defs"].(map[string]any)
+	equationSchema, _ := defs["node:equation"].(map[string]any)
+	if required, _ := equationSchema["required"].([]string); !reflect.DeepEqual(required, []string{"type", "equation"}) {
+		t.Fatalf("expected required fields [type equation]; got %v", equationSchema["required"])
+	}
+
+	err = RegisterSpec(NodeSpec{Type: "panel", Base: "element", Children: []string{"a/b~c"}})
+	if err != nil {
+		t.Fatal("RegisterSpec err:", err)
+	}
+
+	schema = lexical.DefaultNodeTypes.JSONSchema()
+	defs, _ = schema["$defs"].(map[string]any)
+	panelSchema, _ := defs["node:panel"].(map[string]any)
+	children := panelSchema["properties"].(map[string]any)["children"].(map[string]any)
+	expected := map[string]any{"anyOf": []any{map[string]any{"$ref": "#/$defs/node:a~1b~0c"}}}
+	if !reflect.DeepEqual(children["items"], expected) {
+		t.Fatalf("expected children items %v; got %v", expected, children["items"])
+	}
+}
+
+func TestDynamicTextNodes(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&AutoLinkNode{}, &ParagraphNode{}, &TextNode{})
+
+	specs := `
+# text and inline nodes of editor plugins
+- type: keyword
+  base: text
+  fields:
+    - {name: scope, type: string}
+- type: chip
+  base: element
+  inline: true
+  children: [text]
+`
+	err := RegisterSpecsYAML([]byte(specs))
+	if err != nil {
+		t.Fatal("RegisterSpecsYAML err:", err)
+	}
+
+	text := func(text string) string {
+		return `{"detail":0,"format":0,"mode":"normal","style":"","text":"` + text + `","type":"text","version":1}`
+	}
+	keyword := `{"detail":0,"format":1,"mode":"normal","scope":"code","style":"","text":"foo","type":"keyword","version":1}`
+	chip := `{"Children":[` + text("b") + `],"direction":null,"format":"","indent":0,"type":"chip","version":1}`
+	data := document(
+		`{"children":[`+text("a ")+`,`+keyword+`,`+chip+`],"direction":null,"format":"","indent":0,"type":"paragraph","version":1}`,
+		`{"children":[`+text("c")+`],"direction":null,"format":"","indent":0,"type":"paragraph","version":1}`,
+	)
+
+	var root RootNode
+	err = json.Unmarshal([]byte(data), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	err = root.Valid()
+	if err != nil {
+		t.Fatal("Valid err:", err)
+	}
+
+	err = ValidateStructure(&root)
+	if err != nil {
+		t.Fatal("ValidateStructure err:", err)
+	}
+
+	if index := NewTextIndex(&root, UTF16); index.Text() != "a foob\n\nc" {
+		t.Fatalf("expected indexed text %q; got %q", "a foob\n\nc", index.Text())
+	}
+
+	children := root.Root.Children[0].(*ParagraphNode).Children
+	tn, ok := TextNodeOf(children[1])
+	if !ok || tn.Text != "foo" || tn.Format != FormatBold {
+		t.Fatalf("expected bold text node foo of keyword; got %#v", tn)
+	}
+
+	if _, ok := TextNodeOf(children[2]); ok {
+		t.Fatal("expected no text node of chip")
+	}
+
+	if len(*children[2].(Parent).ChildNodes()) != 1 {
+		t.Fatal("expected chip with 1 child")
+	}
+
+	clone := withText(children[1], "bar")
+	if text := children[1].(*DynamicNode).Text(); text != "foo" {
+		t.Fatalf("expected text %s after cloning; got %s", "foo", text)
+	}
+
+	expected := strings.Replace(keyword, `"foo"`, `"bar"`, 1)
+	cloneB, err := json.Marshal(clone)
+	if err != nil {
+		t.Fatal("json.Marshal err:", err)
+	}
+
+	if string(cloneB) != expected {
+		t.Fatalf("expected %s; got %s", expected, cloneB)
+	}
+
+	node, _ := lexical.DefaultNodeTypes.New("chip")
+	err = json.Unmarshal([]byte(chip), node)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	dn := node.(*DynamicNode)
+	if len(dn.Children) != 1 || dn.Properties["Children"] != nil {
+		t.Fatalf("expected chip with 1 child; got %#v", dn)
+	}
+
+	n, err := ReplaceText(&root, "foo", "bar", FindOptions{})
+	if err != nil {
+		t.Fatal("ReplaceText err:", err)
+	}
+
+	children = root.Root.Children[0].(*ParagraphNode).Children
+	if n != 1 || children[1].(*DynamicNode).Text() != "bar" {
+		t.Fatalf("expected text of keyword replaced; got %d replacements", n)
+	}
+
+	if _, ok := children[1].(*DynamicNode); !ok {
+		t.Fatal("expected replaced keyword to stay a keyword")
+	}
+
+	linked := strings.Replace(keyword, `"foo"`, `"see www.example.com now"`, 1)
+	err = json.Unmarshal([]byte(document(`{"children":[`+linked+`],"direction":null,"format":"","indent":0,"type":"paragraph","version":1}`)), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	if n := AutoLinkText(&root, URLMatcher()); n != 1 {
+		t.Fatalf("expected 1 autolink in keyword; got %d", n)
+	}
+
+	children = root.Root.Children[0].(*ParagraphNode).Children
+	if len(children) != 3 || nodeType(children[0]) != "keyword" || nodeType(children[1]) != "autolink" || nodeType(children[2]) != "keyword" {
+		rootB, _ := json.Marshal(&root)
+		t.Fatalf("expected keyword text around autolink; got %s", rootB)
+	}
+
+	if tn, _ := TextNodeOf(children[2]); tn.Text != " now" {
+		t.Fatalf("expected keyword text %q; got %q", " now", tn.Text)
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	yaml := `---
+- type: callout # a comment
+  base: "element"
+  fields:
+  - name: tone
+    type: 'string'
+    required: true
+  - {name: "level", type: integer, required: false}
+  children: [paragraph, "quote", 'it''s']
+  empty: []
+  none:
+- type: equation
+  base: decorator
+  inline: ~
+  size: 1.50
+  "#tag": a # b
+`
+	expected := `[
+		{"type": "callout", "base": "element", "fields": [{"name": "tone", "type": "string", "required": true}, {"name": "level", "type": "integer", "required": false}], "children": ["paragraph", "quote", "it's"], "empty": [], "none": null},
+		{"type": "equation", "base": "decorator", "inline": null, "size": 1.50, "#tag": "a"}
+	]`
+
+	data, err := yamlToJSON([]byte(yaml))
+	if err != nil {
+		t.Fatal("yamlToJSON err:", err)
+	}
+
+	var got, want any
+	json.Unmarshal(data, &got)
+	json.Unmarshal([]byte(expected), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %s; got %s", expected, data)
+	}
+
+	for _, test := range []string{
+		"- type: a\n   base: b\n",
+		"type: a\ntype: b\n",
+		"- type: &a text\n",
+		"- [a, b\n",
+		"- type: \"a\n",
+		"\t- type: a\n",
+	} {
+		if _, err := yamlToJSON([]byte(test)); err == nil {
+			t.Fatalf("yamlToJSON(%q) err is nil; expected non-nil err", test)
+		}
+	}
+}
+
+func TestRegisterSpecReturnsError(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&ParagraphNode{})
+
+	tests := []NodeSpec{
+		{Base: "element"},
+		{Type: "callout", Base: "block"},
+		{Type: "callout", Base: "element", Fields: []FieldSpec{{Name: "children", Type: "array"}}},
+		{Type: "callout", Base: "element", Fields: []FieldSpec{{Name: "Direction", Type: "string"}}},
+		{Type: "callout", Base: "element", Fields: []FieldSpec{{Name: "version", Type: "integer"}}},
+		{Type: "keyword", Base: "text", Fields: []FieldSpec{{Name: "text", Type: "string"}}},
+		{Type: "keyword", Base: "text", Fields: []FieldSpec{{Name: "mode", Type: "string"}}},
+		{Type: "equation", Base: "decorator", Fields: []FieldSpec{{Name: "type", Type: "string"}}},
+		{Type: "callout", Base: "element", Fields: []FieldSpec{{Name: "tone", Type: "enum"}}},
+		{Type: "paragraph", Base: "element"},
+	}
+
+	for _, test := range tests {
+		err := RegisterSpec(test)
+		if err == nil {
+			t.Fatalf("RegisterSpec err is nil for %+v; expected non-nil err", test)
+		}
+	}
+}
+
+type customTextNode struct {
+	TextNode
+}
+
+func (ctn customTextNode) Type() (string, reflect.Type) {
+	return "custom-text", reflect.TypeOf(ctn)
+}
+
+func TestTypeMap(t *testing.T) {
+	lexical.ResetNodes()
+	defer lexical.ResetNodes()
+
+	err := lexical.RegisterNodes(&ParagraphNode{}, &TextNode{}, &LinkNode{})
+	if err != nil {
+		t.Fatal("RegisterNodes err:", err)
+	}
+
+	err = lexical.ReplaceNode("text", &customTextNode{})
+	if err != nil {
+		t.Fatal("ReplaceNode err:", err)
+	}
+
+	err = lexical.UnregisterNode("link")
+	if err != nil {
+		t.Fatal("UnregisterNode err:", err)
+	}
+
+	var names []string
+	for name, nodeType := range lexical.DefaultNodeTypes.Types() {
+		names = append(names, name)
+		if name == "text" && nodeType != reflect.TypeOf(customTextNode{}) {
+			t.Fatalf("expected text type %v; got %v", reflect.TypeOf(customTextNode{}), nodeType)
+		}
+	}
+
+	if !reflect.DeepEqual(names, []string{"paragraph", "text"}) {
+		t.Fatalf("expected types [paragraph text]; got %v", names)
+	}
+
+	lexical.FreezeNodes()
+
+	var root RootNode
+	err = json.Unmarshal([]byte(document(`{"children":[{"text":"Hello","type":"text","version":1}],"type":"paragraph","version":1}`)), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	p := root.Root.Children[0].(*ParagraphNode)
+	if _, ok := p.Children[0].(*customTextNode); !ok {
+		t.Fatalf("expected replaced text node; got %T", p.Children[0])
+	}
+
+	if lexical.RegisterNode(&LinkNode{}) == nil {
+		t.Fatal("RegisterNode err is nil after freeze; expected non-nil err")
+	}
+
+	if lexical.ReplaceNode("text", &TextNode{}) == nil {
+		t.Fatal("ReplaceNode err is nil after freeze; expected non-nil err")
+	}
+
+	if lexical.UnregisterNode("text") == nil {
+		t.Fatal("UnregisterNode err is nil after freeze; expected non-nil err")
+	}
+}
+
+func TestTypeMapReturnsError(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&TextNode{})
+
+	if lexical.RegisterNode(&TextNode{}) == nil {
+		t.Fatal("RegisterNode err is nil for existing type; expected non-nil err")
+	}
+
+	if lexical.ReplaceNode("link", &LinkNode{}) == nil {
+		t.Fatal("ReplaceNode err is nil for missing type; expected non-nil err")
+	}
+
+	if lexical.UnregisterNode("link") == nil {
+		t.Fatal("UnregisterNode err is nil for missing type; expected non-nil err")
+	}
+}
+
+func TestValidateStructure(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&AutoLinkNode{}, &DecoratorNode{}, &LinkNode{}, &MarkNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := `{"text":"Hello","type":"text","version":1}`
+	link := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"link","url":"https://example.com","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+	mark := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"ids":["a"],"type":"mark","version":1}`
+	}
+	decorator := `{"type":"decorator","version":1}`
+
+	tests := []struct {
+		data  string
+		valid bool
+	}{
+		{document(paragraph(text, link(text), mark(text), decorator), decorator), true},
+		{document(text), false},
+		{document(paragraph(paragraph(text))), false},
+		{document(paragraph(link(link(text)))), false},
+		{document(paragraph(link(mark(link(text))))), false},
+		{document(paragraph(link(paragraph(text)))), false},
+		{strings.Replace(document(paragraph(text)), `"type":"root"`, `"type":"paragraph"`, 1), false},
+	}
+
+	for i, test := range tests {
+		var root RootNode
+		err := json.Unmarshal([]byte(test.data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		err = ValidateStructure(&root)
+		if test.valid && err != nil {
+			t.Fatalf("test %d: ValidateStructure err: %v", i, err)
+		}
+
+		if !test.valid && err == nil {
+			t.Fatalf("test %d: ValidateStructure err is nil; expected non-nil err", i)
+		}
+	}
+
+	var root RootNode
+	err := json.Unmarshal([]byte(strings.Replace(document(paragraph(text)), `"type":"root"`, `"type":"paragraph"`, 1)), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	if root.Valid() == nil {
+		t.Fatal("Valid err is nil for root of type paragraph; expected non-nil err")
+	}
+}
+
+func TestRepair(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&HashtagNode{}, &LinkNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := `{"text":"Hello","type":"text","version":1}`
+	empty := `{"text":"","type":"text","version":1}`
+	emptyHashtag := `{"text":"","type":"hashtag","version":1}`
+	link := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"link","url":"https://example.com","version":1}`
+	}
+	data := document(
+		text,
+		link(text),
+		`{"children":[`+link(link(text), empty)+`,`+link(empty)+`,`+emptyHashtag+`],"direction":"up","format":"middle","indent":-2,"type":"paragraph","version":1}`,
+	)
+
+	var root RootNode
+	err := json.Unmarshal([]byte(data), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	fixes := Repair(&root)
+
+	expected := []string{
+		"/2: set indent -2 to 0",
+		`/2: reset invalid direction "up"`,
+		`/2: reset invalid format "middle"`,
+		"/2/0/0: unwrapped nested link",
+		"/2/0/1: removed empty text node",
+		"/2/1/0: removed empty text node",
+		"/2/1: removed empty link",
+		"/2/2: removed empty text node",
+		"/0: wrapped inline text node in paragraph",
+	}
+	var got []string
+	for _, fix := range fixes {
+		got = append(got, fix.String())
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected fixes %v; got %v", expected, got)
+	}
+
+	err = ValidateStructure(&root)
+	if err != nil {
+		t.Fatal("ValidateStructure err:", err)
+	}
+
+	err = root.Valid()
+	if err != nil {
+		t.Fatal("Valid err:", err)
+	}
+
+	if len(root.Root.Children) != 2 {
+		t.Fatalf("expected length of root children 2; got %d", len(root.Root.Children))
+	}
+
+	wrapper := root.Root.Children[0].(*ParagraphNode)
+	if wrapper.NodeType != "paragraph" || wrapper.Version != 1 || len(wrapper.Children) != 2 {
+		t.Fatal("expected paragraph wrapping the text and link nodes")
+	}
+
+	if fixes := Repair(&root); len(fixes) != 0 {
+		t.Fatalf("expected no fixes of repaired document; got %v", fixes)
+	}
+}
+
+func TestLinkNodeValid(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&AutoLinkNode{}, &DecoratorNode{}, &LinkNode{}, &MarkNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := func(s string, format int) string {
+		return `{"format":` + strconv.Itoa(format) + `,"text":"` + s + `","type":"text","version":1}`
+	}
+	mark := `{"children":[` + text("marked", 0) + `],"ids":["a"],"type":"mark","version":1}`
+	decorator := `{"type":"decorator","version":1}`
+	nested := `{"children":[` + text("inner", 0) + `],"type":"link","url":"https://b.com","version":1}`
+	paragraph := `{"children":[` + text("block", 0) + `],"type":"paragraph","version":1}`
+
+	tests := []struct {
+		children []string
+		valid    bool
+	}{
+		{[]string{text("partly ", 0), text("bold", FormatBold)}, true},
+		{[]string{text("marked ", 0), mark, decorator}, true},
+		{[]string{}, false},
+		{[]string{decorator}, false},
+		{[]string{text("outer", 0), nested}, false},
+		{[]string{`{"children":[` + nested + `],"ids":["a"],"type":"mark","version":1}`}, false},
+		{[]string{paragraph}, false},
+	}
+
+	for _, linkType := range []string{"link", "autolink"} {
+		for i, test := range tests {
+			link := `{"children":[` + strings.Join(test.children, ",") + `],"type":"` + linkType + `","url":"https://a.com","version":1}`
+
+			var root RootNode
+			err := json.Unmarshal([]byte(document(`{"children":[`+link+`],"type":"paragraph","version":1}`)), &root)
+			if err != nil {
+				t.Fatal("json.Unmarshal err:", err)
+			}
+
+			err = root.Valid()
+			if test.valid && err != nil {
+				t.Fatalf("%s test %d: Valid err: %v", linkType, i, err)
+			}
+
+			if !test.valid && err == nil {
+				t.Fatalf("%s test %d: Valid err is nil; expected non-nil err", linkType, i)
+			}
+		}
+	}
+}
+
+func TestTextIndex(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&ParagraphNode{}, &TextNode{})
+
+	data := document(
+		`{"children":[{"text":"Hi ","type":"text","version":1},{"format":1,"text":"😀x","type":"text","version":1}],"type":"paragraph","version":1}`,
+		`{"children":[],"type":"paragraph","version":1}`,
+		`{"children":[{"text":"end","type":"text","version":1}],"type":"paragraph","version":1}`,
+	)
+
+	var root RootNode
+	err := json.Unmarshal([]byte(data), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	index := NewTextIndex(&root, UTF16)
+	if index.Text() != "Hi 😀x\n\n\n\nend" {
+		t.Fatalf("expected text %q; got %q", "Hi 😀x\n\n\n\nend", index.Text())
+	}
+
+	if index.Len() != 13 {
+		t.Fatalf("expected length 13; got %d", index.Len())
+	}
+
+	if runes := NewTextIndex(&root, Runes).Len(); runes != 12 {
+		t.Fatalf("expected length in runes 12; got %d", runes)
+	}
+
+	tests := []struct {
+		offset    int
+		position  Position
+		roundTrip bool
+	}{
+		{0, Position{Path{0, 0}, 0}, true},
+		{3, Position{Path{0, 0}, 3}, true},
+		{4, Position{Path{0, 1}, 1}, true},
+		{6, Position{Path{0, 1}, 3}, true},
+		{7, Position{Path{0}, 2}, false},
+		{8, Position{Path{1}, 0}, true},
+		{9, Position{Path{1}, 0}, false},
+		{10, Position{Path{2, 0}, 0}, true},
+		{13, Position{Path{2, 0}, 3}, true},
+	}
+
+	for _, test := range tests {
+		position, err := index.Position(test.offset)
+		if err != nil {
+			t.Fatal("Position err:", err)
+		}
+
+		if position.Path.Compare(test.position.Path) != 0 || position.Offset != test.position.Offset {
+			t.Fatalf("expected position of %d %v:%d; got %v:%d", test.offset, test.position.Path, test.position.Offset, position.Path, position.Offset)
+		}
+
+		offset, err := index.Offset(position)
+		if err != nil {
+			t.Fatal("Offset err:", err)
+		}
+
+		if test.roundTrip && offset != test.offset {
+			t.Fatalf("expected offset of %v:%d %d; got %d", position.Path, position.Offset, test.offset, offset)
+		}
+	}
+
+	for _, offset := range []int{-1, 14} {
+		if _, err := index.Position(offset); err == nil {
+			t.Fatalf("Position err is nil for offset %d; expected non-nil err", offset)
+		}
+	}
+
+	for _, position := range []Position{{Path{0, 1}, 4}, {Path{3}, 0}, {Path{0}, 3}} {
+		if _, err := index.Offset(position); err == nil {
+			t.Fatalf("Offset err is nil for %v:%d; expected non-nil err", position.Path, position.Offset)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&HashtagNode{}, &LinkNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := func(s string, format int) string {
+		return `{"format":` + strconv.Itoa(format) + `,"text":"` + s + `","type":"text","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+	link := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"link","url":"https://a.com","version":1}`
+	}
+	hashtag := func(s string) string {
+		return `{"text":"` + s + `","type":"hashtag","version":1}`
+	}
+
+	unmarshal := func(data string) *RootNode {
+		var root RootNode
+		err := json.Unmarshal([]byte(data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		return &root
+	}
+
+	root := unmarshal(document(
+		paragraph(text("Hello ", 0), text("world", FormatBold)),
+		paragraph(text("foo ", 0), link(text("bar baz", 0)), text(" end", 0)),
+		paragraph(text("last", 0)),
+		paragraph(text("tag ", 0), hashtag("#go")),
+	))
+
+	tests := []struct {
+		start    Position
+		end      Position
+		expected string
+	}{
+		{
+			Position{Path{0, 1}, 2},
+			Position{Path{1, 1, 0}, 3},
+			document(paragraph(text("rld", FormatBold)), paragraph(text("foo ", 0), link(text("bar", 0)))),
+		},
+		{
+			Position{Path{1, 1, 0}, 1},
+			Position{Path{1, 1, 0}, 5},
+			document(paragraph(link(text("ar b", 0)))),
+		},
+		{
+			Position{Path{0, 0}, 0},
+			Position{Path{2, 0}, 4},
+			document(
+				paragraph(text("Hello ", 0), text("world", FormatBold)),
+				paragraph(text("foo ", 0), link(text("bar baz", 0)), text(" end", 0)),
+				paragraph(text("last", 0)),
+			),
+		},
+		{
+			Position{Path{}, 1},
+			Position{Path{}, 2},
+			document(paragraph(text("foo ", 0), link(text("bar baz", 0)), text(" end", 0))),
+		},
+		{
+			Position{Path{3, 1}, 1},
+			Position{Path{3, 1}, 3},
+			document(paragraph(text("go", 0))),
+		},
+		{
+			Position{Path{3, 1}, 0},
+			Position{Path{3, 1}, 2},
+			document(paragraph(hashtag("#g"))),
+		},
+	}
+
+	for i, test := range tests {
+		extracted, err := Extract(root, test.start, test.end)
+		if err != nil {
+			t.Fatalf("test %d: Extract err: %v", i, err)
+		}
+
+		if !extracted.Equal(unmarshal(test.expected)) {
+			extractedB, _ := json.Marshal(extracted)
+			t.Fatalf("test %d: expected %s; got %s", i, test.expected, extractedB)
+		}
+
+		err = extracted.Valid()
+		if err != nil {
+			t.Fatalf("test %d: Valid err: %v", i, err)
+		}
+	}
+
+	index := NewTextIndex(root, UTF16)
+	start, _ := index.Position(8)
+	end, _ := index.Position(19)
+	extracted, err := index.Extract(start, end)
+	if err != nil {
+		t.Fatal("Extract err:", err)
+	}
+
+	if text := NewTextIndex(extracted, UTF16).Text(); text != index.Text()[8:19] {
+		t.Fatalf("expected text %q; got %q", index.Text()[8:19], text)
+	}
+
+	_, err = Extract(root, Position{Path{1, 0}, 0}, Position{Path{0, 0}, 0})
+	if err == nil {
+		t.Fatal("Extract err is nil for start after end; expected non-nil err")
+	}
+}
+
+func TestInsertDocument(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&HashtagNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := func(s string, format int) string {
+		return `{"format":` + strconv.Itoa(format) + `,"mode":"normal","text":"` + s + `","type":"text","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+
+	unmarshal := func(data string) *RootNode {
+		var root RootNode
+		err := json.Unmarshal([]byte(data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		return &root
+	}
+
+	target := document(paragraph(text("Hello world", 0)), paragraph(text("second", 0)))
+	tests := []struct {
+		pos      Position
+		source   string
+		expected string
+	}{
+		{
+			Position{Path{0, 0}, 6},
+			document(paragraph(text("A", FormatBold)), paragraph(text("B", 0)), paragraph(text("C", 0))),
+			document(paragraph(text("Hello ", 0), text("A", FormatBold)), paragraph(text("B", 0)), paragraph(text("Cworld", 0)), paragraph(text("second", 0))),
+		},
+		{
+			Position{Path{0, 0}, 6},
+			document(paragraph(text("big ", 0))),
+			document(paragraph(text("Hello big world", 0)), paragraph(text("second", 0))),
+		},
+		{
+			Position{Path{}, 1},
+			document(paragraph(text("between", 0))),
+			document(paragraph(text("Hello world", 0)), paragraph(text("between", 0)), paragraph(text("second", 0))),
+		},
+		{
+			Position{Path{1, 0}, 6},
+			document(paragraph(text("!", 0))),
+			document(paragraph(text("Hello world", 0)), paragraph(text("second!", 0))),
+		},
+	}
+
+	for i, test := range tests {
+		root := unmarshal(target)
+		err := InsertDocument(root, test.pos, unmarshal(test.source))
+		if err != nil {
+			t.Fatalf("test %d: InsertDocument err: %v", i, err)
+		}
+
+		if !root.Equal(unmarshal(test.expected)) {
+			rootB, _ := json.Marshal(root)
+			t.Fatalf("test %d: expected %s; got %s", i, test.expected, rootB)
+		}
+	}
+
+	err := InsertDocument(unmarshal(target), Position{Path{0, 0}, 12}, unmarshal(target))
+	if err == nil {
+		t.Fatal("InsertDocument err is nil for position out of range; expected non-nil err")
+	}
+
+	first, second := unmarshal(document(paragraph(text("one", 0)))), unmarshal(target)
+	concat := Concat(first, second)
+	expected := unmarshal(document(paragraph(text("one", 0)), paragraph(text("Hello world", 0)), paragraph(text("second", 0))))
+	if !concat.Equal(expected) {
+		t.Fatal("expected concatenation of blocks")
+	}
+
+	concat.Root.Children[0].(*ParagraphNode).Children[0].(*TextNode).Text = "changed"
+	if first.Root.Children[0].(*ParagraphNode).Children[0].(*TextNode).Text != "one" {
+		t.Fatal("expected Concat not to share nodes with its documents")
+	}
+
+	hashtag := func(s string) string {
+		return `{"format":0,"mode":"normal","text":"` + s + `","type":"hashtag","version":1}`
+	}
+	root := unmarshal(document(paragraph(text("a", 0), hashtag("#b"), hashtag(""), text("c", 0), text("d", 0))))
+	Normalize(root)
+	expected = unmarshal(document(paragraph(text("a", 0), hashtag("#b"), text("cd", 0))))
+	if !root.Equal(expected) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected empty hashtag removed and hashtag not merged; got %s", rootB)
+	}
+}
+
+func TestClipboard(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&LinkNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := func(s string, format int) string {
+		return `{"format":` + strconv.Itoa(format) + `,"mode":"normal","text":"` + s + `","type":"text","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+
+	unmarshal := func(data string) *RootNode {
+		var root RootNode
+		err := json.Unmarshal([]byte(data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		return &root
+	}
+
+	root := unmarshal(document(paragraph(text("Hello ", 0), text("bold", FormatBold)), paragraph(text("second", 0))))
+	clipboard, err := CopyRange(root, Position{Path{0, 0}, 2}, Position{Path{0, 1}, 2}, "editor")
+	if err != nil {
+		t.Fatal("CopyRange err:", err)
+	}
+
+	data, err := json.Marshal(clipboard)
+	if err != nil {
+		t.Fatal("json.Marshal err:", err)
+	}
+
+	parsed, err := ParseClipboard(data, lexical.DefaultLimits)
+	if err != nil {
+		t.Fatal("ParseClipboard err:", err)
+	}
+
+	if parsed.Namespace != "editor" || len(parsed.Nodes) != 2 {
+		t.Fatalf("expected 2 inline nodes in namespace editor; got %d in %s", len(parsed.Nodes), parsed.Namespace)
+	}
+
+	err = InsertClipboard(root, Position{Path{1, 0}, 3}, parsed)
+	if err != nil {
+		t.Fatal("InsertClipboard err:", err)
+	}
+
+	expected := unmarshal(document(
+		paragraph(text("Hello ", 0), text("bold", FormatBold)),
+		paragraph(text("secllo ", 0), text("bo", FormatBold), text("ond", 0)),
+	))
+	if !root.Equal(expected) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected pasted inline nodes; got %s", rootB)
+	}
+
+	clipboard, err = CopyRange(root, Position{Path{}, 0}, Position{Path{}, 1}, "editor")
+	if err != nil {
+		t.Fatal("CopyRange err:", err)
+	}
+
+	if len(clipboard.Nodes) != 1 || nodeType(clipboard.Nodes[0]) != "paragraph" {
+		t.Fatal("expected copied paragraph")
+	}
+
+	_, err = ParseClipboard([]byte(`{"namespace":"editor","nodes":[{"type":"unknown"}]}`), lexical.DefaultLimits)
+	if err == nil {
+		t.Fatal("ParseClipboard err is nil for unknown node type; expected non-nil err")
+	}
+
+	many := `{"namespace":"editor","nodes":[` + strings.Repeat(text("a", 0)+",", 49) + text("a", 0) + `]}`
+	tests := []struct {
+		limit  string
+		limits lexical.Limits
+		data   string
+	}{
+		{limit: "MaxNodes", limits: lexical.Limits{MaxNodes: 5}, data: many},
+		{limit: "MaxChildren", limits: lexical.Limits{MaxChildren: 5}, data: many},
+		{limit: "MaxDepth", limits: lexical.Limits{MaxDepth: 1}, data: `{"namespace":"editor","nodes":[` + paragraph(text("a", 0)) + `]}`},
+	}
+
+	for _, test := range tests {
+		_, err = ParseClipboard([]byte(test.data), test.limits)
+		var limitErr *lexical.LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != test.limit {
+			t.Fatalf("expected %s limit error; got %v", test.limit, err)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&LinkNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := func(s string, format int) string {
+		return `{"format":` + strconv.Itoa(format) + `,"mode":"normal","text":"` + s + `","type":"text","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+	link := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"link","url":"https://a.com","version":1}`
+	}
+
+	unmarshal := func(data string) *RootNode {
+		var root RootNode
+		err := json.Unmarshal([]byte(data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		return &root
+	}
+
+	root := unmarshal(document(
+		paragraph(text("The ", 0), text("quick brown", FormatBold), text(" fox ", 0), link(text("jumps over", 0))),
+		paragraph(text("the lazy dog", 0)),
+	))
+
+	tests := []struct {
+		max      int
+		opts     TruncateOptions
+		expected string
+	}{
+		{12, TruncateOptions{}, document(paragraph(text("The ", 0), text("quick", FormatBold), text("…", 0)))},
+		{25, TruncateOptions{}, document(paragraph(text("The ", 0), text("quick brown", FormatBold), text(" fox ", 0), link(text("jumps", 0)), text("…", 0)))},
+		{2, TruncateOptions{}, document(paragraph(text("Th", 0), text("…", 0)))},
+		{7, TruncateOptions{By: TruncateWords, Ellipsis: "..."}, document(
+			paragraph(text("The ", 0), text("quick brown", FormatBold), text(" fox ", 0), link(text("jumps over", 0))),
+			paragraph(text("the", 0), text("...", 0)),
+		)},
+		{1, TruncateOptions{By: TruncateBlocks}, document(
+			paragraph(text("The ", 0), text("quick brown", FormatBold), text(" fox ", 0), link(text("jumps over", 0)), text("…", 0)),
+		)},
+		{100, TruncateOptions{}, document(
+			paragraph(text("The ", 0), text("quick brown", FormatBold), text(" fox ", 0), link(text("jumps over", 0))),
+			paragraph(text("the lazy dog", 0)),
+		)},
+	}
+
+	for i, test := range tests {
+		truncated := Truncate(root, test.max, test.opts)
+		if !truncated.Equal(unmarshal(test.expected)) {
+			truncatedB, _ := json.Marshal(truncated)
+			t.Fatalf("test %d: expected %s; got %s", i, test.expected, truncatedB)
+		}
+
+		err := truncated.Valid()
+		if err != nil {
+			t.Fatalf("test %d: Valid err: %v", i, err)
+		}
+	}
+}
+
+func TestChunkDocument(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&ParagraphNode{}, &TextNode{})
+
+	paragraph := func(s string) string {
+		return `{"children":[{"text":"` + s + `","type":"text","version":1}],"type":"paragraph","version":1}`
+	}
+
+	var root RootNode
+	err := json.Unmarshal([]byte(document(
+		paragraph("First sentence here. Second one follows."),
+		paragraph("Short."),
+		paragraph("abcdefghijklmnopqrstuvwxyz0123"),
+	)), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	tests := []struct {
+		opts     ChunkOptions
+		expected []string
+	}{
+		{ChunkOptions{MaxSize: 25}, []string{"First sentence here.", "Second one follows.", "Short.", "abcdefghijklmnopqrstuvwxy", "z0123"}},
+		{ChunkOptions{MaxSize: 25, Overlap: 8}, []string{"First sentence here.", "here. Second one follows.", "follows.\n\nShort.", "abcdefghijklmnopqrstuvwxy", "z0123"}},
+		{ChunkOptions{MaxSize: 100}, []string{"First sentence here. Second one follows.\n\nShort.\n\nabcdefghijklmnopqrstuvwxyz0123"}},
+	}
+
+	for i, test := range tests {
+		chunks, err := ChunkDocument(&root, test.opts)
+		if err != nil {
+			t.Fatalf("test %d: ChunkDocument err: %v", i, err)
+		}
+
+		var texts []string
+		for _, chunk := range chunks {
+			texts = append(texts, chunk.Text)
+		}
+
+		if !reflect.DeepEqual(texts, test.expected) {
+			t.Fatalf("test %d: expected chunks %q; got %q", i, test.expected, texts)
+		}
+	}
+
+	chunks, _ := ChunkDocument(&root, ChunkOptions{MaxSize: 25, Overlap: 8})
+	sources := chunks[2].Sources
+	if len(sources) != 2 || sources[0].Block.String() != "/0" || sources[1].Block.String() != "/1" {
+		t.Fatalf("expected sources /0 and /1; got %v", sources)
+	}
+
+	if sources[0].Start.Path.String() != "/0/0" || sources[0].Start.Offset != 32 || sources[1].End.Offset != 6 {
+		t.Fatalf("expected source offsets 32 and 6; got %d and %d", sources[0].Start.Offset, sources[1].End.Offset)
+	}
+
+	for _, opts := range []ChunkOptions{{}, {MaxSize: 10, Overlap: 10}} {
+		if _, err := ChunkDocument(&root, opts); err == nil {
+			t.Fatalf("ChunkDocument err is nil for %+v; expected non-nil err", opts)
+		}
+	}
+}
+
+func TestMarkRange(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&HashtagNode{}, &MarkNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := func(s string, format int) string {
+		return `{"format":` + strconv.Itoa(format) + `,"text":"` + s + `","type":"text","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+	mark := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"ids":["c1"],"type":"mark","version":1}`
+	}
+
+	unmarshal := func(data string) *RootNode {
+		var root RootNode
+		err := json.Unmarshal([]byte(data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		return &root
+	}
+
+	root := unmarshal(document(paragraph(text("Hello ", 0), text("bold", FormatBold)), paragraph(text("second", 0))))
+	err := MarkRange(root, Position{Path{0, 0}, 2}, Position{Path{1, 0}, 3}, []string{"c1"})
+	if err != nil {
+		t.Fatal("MarkRange err:", err)
+	}
+
+	expected := unmarshal(document(
+		paragraph(text("He", 0), mark(text("llo ", 0)), mark(text("bold", FormatBold))),
+		paragraph(mark(text("sec", 0)), text("ond", 0)),
+	))
+	if !root.Equal(expected) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected marked range; got %s", rootB)
+	}
+
+	err = MarkRange(root, Position{Path{1, 1}, 0}, Position{Path{0, 0}, 0}, []string{"c1"})
+	if err == nil {
+		t.Fatal("MarkRange err is nil for start after end; expected non-nil err")
+	}
+
+	hashtag := func(s string) string {
+		return `{"format":0,"text":"` + s + `","type":"hashtag","version":1}`
+	}
+	root = unmarshal(document(paragraph(text("see ", 0), hashtag("#go"))))
+	err = MarkRange(root, Position{Path{0, 1}, 1}, Position{Path{0, 1}, 3}, []string{"c1"})
+	if err != nil {
+		t.Fatal("MarkRange err:", err)
+	}
+
+	expected = unmarshal(document(paragraph(text("see ", 0), hashtag("#"), mark(text("go", 0)))))
+	if !root.Equal(expected) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected marked part of hashtag as text; got %s", rootB)
+	}
+
+	err = root.Valid()
+	if err != nil {
+		t.Fatal("Valid err:", err)
+	}
+}
+
+func TestReplaceText(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&HashtagNode{}, &MentionNode{}, &ParagraphNode{}, &TextNode{})
+
+	text := func(s string, format int, mode string) string {
+		return `{"format":` + strconv.Itoa(format) + `,"mode":"` + mode + `","text":"` + s + `","type":"text","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+
+	unmarshal := func(data string) *RootNode {
+		var root RootNode
+		err := json.Unmarshal([]byte(data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		return &root
+	}
+
+	data := document(paragraph(
+		text("hel", FormatBold, "normal"),
+		text("lo world", 0, "normal"),
+		text("hello", 0, "token"),
+		text("Hello", FormatItalic, "normal"),
+	))
+
+	matches, err := FindText(unmarshal(data), "HELLO", FindOptions{IgnoreCase: true})
+	if err != nil {
+		t.Fatal("FindText err:", err)
+	}
+
+	expected := []TextMatch{
+		{Text: "hello", Start: Position{Path{0, 0}, 0}, End: Position{Path{0, 1}, 2}},
+		{Text: "Hello", Start: Position{Path{0, 3}, 0}, End: Position{Path{0, 3}, 5}},
+	}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Fatalf("expected matches %v; got %v", expected, matches)
+	}
+
+	if matches, _ := FindText(unmarshal(data), "HELLO", FindOptions{}); len(matches) != 0 {
+		t.Fatalf("expected no case sensitive matches; got %d", len(matches))
+	}
+
+	root := unmarshal(data)
+	n, err := ReplaceText(root, "hello", "goodbye", FindOptions{IgnoreCase: true})
+	if err != nil {
+		t.Fatal("ReplaceText err:", err)
+	}
+
+	if n != 2 {
+		t.Fatalf("expected 2 replacements; got %d", n)
+	}
+
+	replaced := unmarshal(document(paragraph(
+		text("goodbye", FormatBold, "normal"),
+		text(" world", 0, "normal"),
+		text("hello", 0, "token"),
+		text("goodbye", FormatItalic, "normal"),
+	)))
+	if !root.Equal(replaced) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected replaced document; got %s", rootB)
+	}
+
+	root = unmarshal(document(paragraph(text("mail ann", 0, "normal"), text("@example.com now", FormatBold, "normal"))))
+	_, err = ReplaceText(root, `(\w+)@example\.com`, "${1} at example", FindOptions{Regexp: true})
+	if err != nil {
+		t.Fatal("ReplaceText err:", err)
+	}
+
+	replaced = unmarshal(document(paragraph(text("mail ann at example", 0, "normal"), text(" now", FormatBold, "normal"))))
+	if !root.Equal(replaced) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected expanded replacement; got %s", rootB)
+	}
+
+	if _, err := ReplaceText(root, "(", "", FindOptions{Regexp: true}); err == nil {
+		t.Fatal("ReplaceText err is nil for invalid pattern; expected non-nil err")
+	}
+
+	hashtag := `{"format":0,"mode":"normal","text":"#foo","type":"hashtag","version":1}`
+	mention := `{"format":0,"mentionName":"ann","mode":"segmented","text":"@ann","type":"mention","version":1}`
+	data = document(paragraph(text("see ", 0, "normal"), hashtag, text(" #foo ", 0, "normal"), mention))
+	root = unmarshal(data)
+	matches, err = FindText(root, "#foo|@ann|see #", FindOptions{Regexp: true})
+	if err != nil {
+		t.Fatal("FindText err:", err)
+	}
+
+	expected = []TextMatch{{Text: "#foo", Start: Position{Path{0, 2}, 1}, End: Position{Path{0, 2}, 5}}}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Fatalf("expected matches %v outside text entities; got %v", expected, matches)
+	}
+
+	n, err = ReplaceText(root, "#foo", "bar", FindOptions{})
+	if err != nil {
+		t.Fatal("ReplaceText err:", err)
+	}
+
+	replaced = unmarshal(document(paragraph(text("see ", 0, "normal"), hashtag, text(" bar ", 0, "normal"), mention)))
+	if n != 1 || !root.Equal(replaced) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected hashtag and mention untouched; got %s", rootB)
+	}
+
+	err = root.Valid()
+	if err != nil {
+		t.Fatal("Valid err:", err)
+	}
+}
+
+func TestAutoLinkText(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&ParagraphNode{}, &TextNode{}, &AutoLinkNode{}, &HashtagNode{}, &MentionNode{})
+
+	text := func(s string, format int) string {
+		return `{"format":` + strconv.Itoa(format) + `,"mode":"normal","text":"` + s + `","type":"text","version":1}`
+	}
+	autolink := func(url string, unlinked bool, children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"isUnlinked":` + strconv.FormatBool(unlinked) + `,"type":"autolink","url":"` + url + `","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+
+	unmarshal := func(data string) *RootNode {
+		var root RootNode
+		err := json.Unmarshal([]byte(data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		return &root
+	}
+
+	root := unmarshal(document(
+		paragraph(text("Visit www.exa", 0), text("mple.com today #go", FormatBold)),
+		paragraph(
+			text("ping @ann_lee or ann@example.com, #1 x", 0),
+			autolink("https://www.skip.com", true, text("www.skip.com", 0)),
+			text(" www.code.com", FormatCode),
+		),
+	))
+
+	n := AutoLinkText(root, DefaultTextMatchers()...)
+	if n != 4 {
+		t.Fatalf("expected 4 created nodes; got %d", n)
+	}
+
+	expected := unmarshal(document(
+		paragraph(
+			text("Visit ", 0),
+			autolink("https://www.example.com", false, text("www.exa", 0), text("mple.com", FormatBold)),
+			text(" today ", FormatBold),
+			`{"format":1,"mode":"normal","text":"#go","type":"hashtag","version":1}`,
+		),
+		paragraph(
+			text("ping ", 0),
+			`{"mentionName":"ann_lee","mode":"segmented","text":"@ann_lee","type":"mention","version":1}`,
+			text(" or ", 0),
+			autolink("mailto:ann@example.com", false, text("ann@example.com", 0)),
+			text(", #1 x", 0),
+			autolink("https://www.skip.com", true, text("www.skip.com", 0)),
+			text(" www.code.com", FormatCode),
+		),
+	))
+	if !root.Equal(expected) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected linked document; got %s", rootB)
+	}
+
+	if err := root.Valid(); err != nil {
+		t.Fatalf("expected valid document; got %v", err)
+	}
+
+	if text := NewTextIndex(root, UTF16).Text(); !strings.HasPrefix(text, "Visit www.example.com today #go\n\nping @ann_lee") {
+		t.Fatalf("expected text content of hashtags and mentions; got %q", text)
+	}
+
+	if n := AutoLinkText(root, DefaultTextMatchers()...); n != 0 {
+		t.Fatalf("expected no nodes created twice; got %d", n)
+	}
+
+	root = unmarshal(document(paragraph(text("See www.example.com. Or www.example.org/a?, then more", 0))))
+	AutoLinkText(root, URLMatcher())
+
+	expected = unmarshal(document(paragraph(
+		text("See ", 0),
+		autolink("https://www.example.com", false, text("www.example.com", 0)),
+		text(". Or ", 0),
+		autolink("https://www.example.org/a", false, text("www.example.org/a", 0)),
+		text("?, then more", 0),
+	)))
+	if !root.Equal(expected) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected links without trailing punctuation; got %s", rootB)
+	}
+}
+
+func TestEmbeddedTextNodes(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&MarkNode{}, &ParagraphNode{}, &TextNode{}, &HashtagNode{}, &MentionNode{})
+
+	text := func(s string) string {
+		return `{"mode":"normal","text":"` + s + `","type":"text","version":1}`
+	}
+	hashtag := func(s string) string {
+		return `{"mode":"normal","text":"` + s + `","type":"hashtag","version":1}`
+	}
+	mention := func(name, s string) string {
+		return `{"mentionName":"` + name + `","mode":"segmented","text":"` + s + `","type":"mention","version":1}`
+	}
+	paragraph := func(children ...string) string {
+		return `{"children":[` + strings.Join(children, ",") + `],"type":"paragraph","version":1}`
+	}
+
+	unmarshal := func(data string) *RootNode {
+		var root RootNode
+		err := json.Unmarshal([]byte(data), &root)
+		if err != nil {
+			t.Fatal("json.Unmarshal err:", err)
+		}
+
+		return &root
+	}
+
+	tn, ok := TextNodeOf(NewHashtagNode("#go"))
+	if !ok || tn.Text != "#go" {
+		t.Fatal("expected text node of hashtag node")
+	}
+
+	old := unmarshal(document(paragraph(text("See "), hashtag("#go"), text(" "), mention("ann", "@ann"))))
+	new := unmarshal(document(paragraph(text("See "), hashtag("#golang"), text(" "), mention("ann.lee", "@ann"))))
+	changes := Diff(old, new)
+	if len(changes) != 2 || changes[0].Kind != TextChanged || changes[1].Kind != NodeChanged {
+		t.Fatalf("expected text change of hashtag and node change of mention; got %v", changes)
+	}
+
+	redline := Redline(old, new)
+	if _, ok := (*redline.Root.Children[0].(Parent).ChildNodes())[1].(*HashtagNode); !ok {
+		t.Fatal("expected redline to keep hashtag node")
+	}
+
+	base := unmarshal(document(paragraph(hashtag("#release"))))
+	ours := unmarshal(document(paragraph(hashtag("#release_notes"))))
+	theirs := unmarshal(document(paragraph(hashtag("#new_release"))))
+	merged, conflicts := Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts; got %v", conflicts)
+	}
+
+	mergedTag, ok := (*merged.Root.Children[0].(Parent).ChildNodes())[0].(*HashtagNode)
+	if !ok || mergedTag.Text != "#new_release_notes" {
+		t.Fatalf("expected merged hashtag #new_release_notes; got %v", merged.Root.Children[0])
+	}
+}
@@ -0,0 +1,50 @@
+package nodes
+
+// Normalize merges adjacent text nodes with the same format, style, mode and detail and
+// removes empty text nodes, as Lexical does when it normalizes text. Token and segmented
+// text nodes and nodes embedding text nodes, such as hashtags and mentions, are not merged.
+func Normalize(root *RootNode) {
+	normalize(&root.Root)
+}
+
+func normalize(node Parent) {
+	children := node.ChildNodes()
+	normalized := (*children)[:0]
+	for _, child := range *children {
+		if parent, ok := child.(Parent); ok {
+			normalize(parent)
+		}
+
+		tn, ok := TextNodeOf(child)
+		if !ok {
+			normalized = append(normalized, child)
+			continue
+		}
+
+		if tn.Text == "" {
+			continue
+		}
+
+		if n := len(normalized); n > 0 {
+			prev, ok := normalized[n-1].(*TextNode)
+			if _, plain := child.(*TextNode); ok && plain && mergeableText(prev, tn) {
+				prev.Text += tn.Text
+				continue
+			}
+		}
+
+		normalized = append(normalized, child)
+	}
+
+	clear((*children)[len(normalized):])
+	*children = normalized
+}
+
+func mergeableText(a, b *TextNode) bool {
+	return simpleText(a) && simpleText(b) &&
+		a.Format == b.Format && a.Style == b.Style && a.Mode == b.Mode && a.Detail == b.Detail
+}
+
+func simpleText(tn *TextNode) bool {
+	return tn.Mode == "" || tn.Mode == "normal"
+}
@@ -30,6 +30,11 @@ func (pn ParagraphNode) Type() (string, reflect.Type) {
 	return "paragraph", reflect.TypeOf(pn)
 }
 
+// JSONType returns the type of paragraph node, which is decoded from JSON directly
+func (pn ParagraphNode) JSONType() reflect.Type {
+	return reflect.TypeOf(pn)
+}
+
 // Unmarshal unmarshals the paragraph node
 func (pn *ParagraphNode) Unmarshal(data map[string]interface{}) error {
 	pnB, err := json.Marshal(data)
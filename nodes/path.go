@@ -0,0 +1,117 @@
+package nodes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// Parent is implemented by nodes that have children
+type Parent interface {
+	lexical.Node
+	ChildNodes() *lexical.NodeArray
+}
+
+// Path is the location of a node given as the child indexes leading to it from the root.
+// The empty path is the root element.
+type Path []int
+
+// Child returns the path of the i-th child of the node at path
+func (p Path) Child(i int) Path {
+	child := make(Path, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, i)
+}
+
+// Parent returns the path of the parent of the node at path
+func (p Path) Parent() Path {
+	if len(p) == 0 {
+		return nil
+	}
+
+	return p[: len(p)-1 : len(p)-1]
+}
+
+// Compare returns -1, 0 or 1 when path is before, equal to or after other in document order
+func (p Path) Compare(other Path) int {
+	for i := 0; i < len(p) && i < len(other); i++ {
+		switch {
+		case p[i] < other[i]:
+			return -1
+		case p[i] > other[i]:
+			return 1
+		}
+	}
+
+	switch {
+	case len(p) < len(other):
+		return -1
+	case len(p) > len(other):
+		return 1
+	}
+
+	return 0
+}
+
+// String returns the path as slash separated child indexes, or the empty string for the nil
+// path, such as the old path of an inserted node, so that it is not mistaken for the root
+func (p Path) String() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.key()
+}
+
+// key returns the path as slash separated child indexes, the same for nil and empty paths
+func (p Path) key() string {
+	parts := make([]string, len(p))
+	for i, index := range p {
+		parts[i] = strconv.Itoa(index)
+	}
+
+	return "/" + strings.Join(parts, "/")
+}
+
+// NodeAt returns the node at path
+func (rn *RootNode) NodeAt(path Path) (lexical.Node, error) {
+	var node lexical.Node = &rn.Root
+	for depth, index := range path {
+		parent, ok := node.(Parent)
+		if !ok {
+			return nil, fmt.Errorf("%s: node at %v has no children", pkg, path[:depth])
+		}
+
+		children := *parent.ChildNodes()
+		if index < 0 || index >= len(children) {
+			return nil, fmt.Errorf("%s: no node at %v", pkg, path[:depth+1])
+		}
+
+		node = children[index]
+	}
+
+	return node, nil
+}
+
+// Walk calls fn for every node of the document in document order, starting with the root element.
+// The children of a node are skipped when fn returns false.
+func Walk(root *RootNode, fn func(node lexical.Node, path Path) bool) {
+	walk(&root.Root, Path{}, fn)
+}
+
+func walk(node lexical.Node, path Path, fn func(node lexical.Node, path Path) bool) {
+	if !fn(node, path) {
+		return
+	}
+
+	parent, ok := node.(Parent)
+	if !ok {
+		return
+	}
+
+	for i, child := range *parent.ChildNodes() {
+		walk(child, path.Child(i), fn)
+	}
+}
@@ -0,0 +1,124 @@
+package nodes
+
+import "github.com/tylertravisty/go-lexical"
+
+// Mark ids used by Redline to tag inserted and deleted content
+const (
+	RedlineInsert = "insert"
+	RedlineDelete = "delete"
+)
+
+// Redline merges two documents into one where content only in new is wrapped in
+// mark nodes with the RedlineInsert id and content only in old is wrapped in mark
+// nodes with the RedlineDelete id. Text keeps the formatting of the version it comes from.
+// Inserted and deleted empty blocks, such as empty paragraphs, hold an empty mark node, and
+// inserted and deleted blocks without children, such as decorators, are wrapped in a mark
+// node in a paragraph, so that the redline follows the content model. Changes of only the
+// format or style of text are not marked; use Diff to find them.
+func Redline(old, new *RootNode) *RootNode {
+	root, _ := shell(&new.Root).(*ElementNode)
+	root.Children = redlineNodes(old.Root.Children, new.Root.Children, false)
+	return &RootNode{Root: *root}
+}
+
+// RedlineHTML renders the redline of two documents as HTML, with inserted content in
+// <ins> and deleted content in <del> elements
+func RedlineHTML(old, new *RootNode) string {
+	return HTML(Redline(old, new))
+}
+
+func redlineNodes(old, new lexical.NodeArray, inline bool) lexical.NodeArray {
+	var nodes lexical.NodeArray
+	for _, op := range alignNodes(old, new, false) {
+		switch op.kind {
+		case nodeEqual:
+			nodes = append(nodes, Clone(new[op.b]))
+		case nodeInsert:
+			nodes = append(nodes, redlineMark(new[op.b], RedlineInsert, inline))
+		case nodeDelete:
+			nodes = append(nodes, redlineMark(old[op.a], RedlineDelete, inline))
+		case nodeModify:
+			nodes = append(nodes, redlineNode(old[op.a], new[op.b], inline)...)
+		}
+	}
+
+	return nodes
+}
+
+func redlineNode(old, new lexical.Node, inline bool) lexical.NodeArray {
+	oldText, oldIsText := TextNodeOf(old)
+	newText, newIsText := TextNodeOf(new)
+	if oldIsText && newIsText && textOnly(old, new) {
+		if oldText.Text == newText.Text {
+			return lexical.NodeArray{Clone(new)}
+		}
+
+		var nodes lexical.NodeArray
+		for _, edit := range diffText(oldText.Text, newText.Text) {
+			switch edit.Op {
+			case TextEqual:
+				nodes = append(nodes, withText(new, edit.Text))
+			case TextInsert:
+				nodes = append(nodes, NewMarkNode([]string{RedlineInsert}, withText(new, edit.Text)))
+			case TextDelete:
+				nodes = append(nodes, NewMarkNode([]string{RedlineDelete}, withText(old, edit.Text)))
+			}
+		}
+
+		return nodes
+	}
+
+	oldParent, oldIsParent := old.(Parent)
+	newParent, newIsParent := new.(Parent)
+	oldLink, oldIsLink := old.(interface{ link() *LinkNode })
+	newLink, newIsLink := new.(interface{ link() *LinkNode })
+	sameURL := !oldIsLink || !newIsLink || oldLink.link().URL == newLink.link().URL
+	if oldIsParent && newIsParent && sameURL {
+		parent := shell(newParent)
+		*parent.ChildNodes() = redlineNodes(*oldParent.ChildNodes(), *newParent.ChildNodes(), true)
+		return lexical.NodeArray{parent}
+	}
+
+	return lexical.NodeArray{
+		redlineMark(old, RedlineDelete, inline),
+		redlineMark(new, RedlineInsert, inline),
+	}
+}
+
+// redlineMark wraps an inline node in a mark node, or the children of a block node. An empty
+// block holds an empty mark node, and a block without children is wrapped in a mark node in
+// a paragraph.
+func redlineMark(node lexical.Node, id string, inline bool) lexical.Node {
+	if inline {
+		return NewMarkNode([]string{id}, Clone(node))
+	}
+
+	parent, ok := node.(Parent)
+	if !ok {
+		return &ParagraphNode{ElementNode: ElementNode{
+			BaseNode: BaseNode{NodeType: "paragraph", Version: 1},
+			Children: lexical.NodeArray{NewMarkNode([]string{id}, Clone(node))},
+		}}
+	}
+
+	block := shell(parent)
+	if len(*parent.ChildNodes()) == 0 {
+		*block.ChildNodes() = lexical.NodeArray{NewMarkNode([]string{id})}
+	}
+
+	for _, child := range *parent.ChildNodes() {
+		*block.ChildNodes() = append(*block.ChildNodes(), redlineMark(child, id, true))
+	}
+
+	return block
+}
+
+// withText returns a copy of node, a text node or a node embedding one, holding text
+func withText[T lexical.Node](node T, text string) T {
+	clone, _ := Clone(node).(T)
+	if tn, ok := TextNodeOf(clone); ok {
+		tn.Text = text
+	}
+
+	return clone
+}
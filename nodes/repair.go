@@ -0,0 +1,132 @@
+package nodes
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// Fix describes a change made by Repair
+type Fix struct {
+	// Path is the location of the fixed node in the document before it was repaired
+	Path        Path
+	Description string
+}
+
+func (f Fix) String() string {
+	return f.Path.String() + ": " + f.Description
+}
+
+// Repair fixes common invalid shapes of the document in place and returns the fixes it made.
+// It wraps inline nodes at the root in paragraphs, unwraps nested links, removes empty links
+// and empty text nodes, and resets negative indents and invalid directions and formats.
+func Repair(root *RootNode) []Fix {
+	r := &repairer{}
+	if root.Root.NodeType != "root" {
+		r.fix(Path{}, fmt.Sprintf("set root type %q to root", root.Root.NodeType))
+		root.Root.NodeType = "root"
+	}
+
+	r.repairElement(&root.Root, Path{})
+	children, origins := r.repairChildren(root.Root.Children, Path{}, false)
+	root.Root.Children = r.wrapInline(children, origins)
+	return r.fixes
+}
+
+type repairer struct {
+	fixes []Fix
+}
+
+func (r *repairer) fix(path Path, description string) {
+	r.fixes = append(r.fixes, Fix{Path: path, Description: description})
+}
+
+func (r *repairer) repairElement(en *ElementNode, path Path) {
+	if en.Indent < 0 {
+		r.fix(path, fmt.Sprintf("set indent %d to 0", en.Indent))
+		en.Indent = 0
+	}
+
+	if en.Direction != nil && !slices.Contains(elementDirections, *en.Direction) {
+		r.fix(path, fmt.Sprintf("reset invalid direction %q", *en.Direction))
+		en.Direction = nil
+	}
+
+	if !slices.Contains(elementFormats, en.Format) {
+		r.fix(path, fmt.Sprintf("reset invalid format %q", en.Format))
+		en.Format = ""
+	}
+}
+
+// repairChildren returns the repaired children of the node at path and their paths before
+// the repair. Links inside inLink are nested.
+func (r *repairer) repairChildren(children lexical.NodeArray, path Path, inLink bool) (lexical.NodeArray, []Path) {
+	repaired := children[:0:0]
+	var origins []Path
+	for i, child := range children {
+		childPath := path.Child(i)
+		if child == nil {
+			r.fix(childPath, "removed nil node")
+			continue
+		}
+
+		if tn, ok := TextNodeOf(child); ok && tn.Text == "" {
+			r.fix(childPath, "removed empty text node")
+			continue
+		}
+
+		if e, ok := child.(interface{ element() *ElementNode }); ok {
+			r.repairElement(e.element(), childPath)
+		}
+
+		_, isLink := child.(interface{ link() *LinkNode })
+		if parent, ok := child.(Parent); ok {
+			grandchildren, grandchildOrigins := r.repairChildren(*parent.ChildNodes(), childPath, inLink || isLink)
+			*parent.ChildNodes() = grandchildren
+
+			if isLink && inLink {
+				r.fix(childPath, "unwrapped nested link")
+				repaired = append(repaired, grandchildren...)
+				origins = append(origins, grandchildOrigins...)
+				continue
+			}
+
+			if isLink && len(grandchildren) == 0 {
+				r.fix(childPath, "removed empty link")
+				continue
+			}
+		}
+
+		repaired = append(repaired, child)
+		origins = append(origins, childPath)
+	}
+
+	return repaired, origins
+}
+
+// wrapInline wraps runs of inline-only nodes among the root children in paragraphs
+func (r *repairer) wrapInline(children lexical.NodeArray, origins []Path) lexical.NodeArray {
+	wrapped := make(lexical.NodeArray, 0, len(children))
+	var paragraph *ParagraphNode
+	for i, child := range children {
+		rule, exists := DefaultContentModel[nodeType(child)]
+		if !exists || !rule.Inline || rule.Block {
+			paragraph = nil
+			wrapped = append(wrapped, child)
+			continue
+		}
+
+		if paragraph == nil {
+			r.fix(origins[i], fmt.Sprintf("wrapped inline %s node in paragraph", nodeType(child)))
+			paragraph = &ParagraphNode{
+				ElementNode: ElementNode{BaseNode: BaseNode{NodeType: "paragraph", Version: 1}},
+			}
+			wrapped = append(wrapped, paragraph)
+		}
+
+		paragraph.Children = append(paragraph.Children, child)
+	}
+
+	return wrapped
+}
@@ -0,0 +1,230 @@
+package nodes
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// FindOptions are the options of FindText and ReplaceText
+type FindOptions struct {
+	// Regexp interprets the pattern as a regular expression and expands $ references in the
+	// replacement as regexp.Regexp.Expand does; otherwise both are literal
+	Regexp bool
+	// IgnoreCase matches without regard to case
+	IgnoreCase bool
+}
+
+// TextMatch is a match of a pattern in the text of a document
+type TextMatch struct {
+	Text string
+	// Start and End are the positions of the match, with offsets in UTF-16 code units
+	Start Position
+	End   Position
+}
+
+// textRunNodes is a run of adjacent sibling text nodes
+type textRunNodes struct {
+	// owners are the nodes of the run, which are or embed its text nodes
+	owners lexical.NodeArray
+	nodes  []*TextNode
+	// index is the index of the first node in its parent
+	index int
+	text  string
+}
+
+// FindText returns the non-empty matches of pattern in the text of root. A match can span
+// adjacent text nodes of different formats but not text nodes in token mode or text entities,
+// such as hashtags and mentions, whose text is not matched.
+func FindText(root *RootNode, pattern string, opts FindOptions) ([]TextMatch, error) {
+	re, err := compileFind(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []TextMatch
+	Walk(root, func(node lexical.Node, path Path) bool {
+		parent, ok := node.(Parent)
+		if !ok {
+			return true
+		}
+
+		for _, run := range textRuns(*parent.ChildNodes(), editableText) {
+			for _, loc := range re.FindAllStringIndex(run.text, -1) {
+				if loc[0] == loc[1] {
+					continue
+				}
+
+				matches = append(matches, TextMatch{
+					Text:  run.text[loc[0]:loc[1]],
+					Start: run.position(path, loc[0], false),
+					End:   run.position(path, loc[1], true),
+				})
+			}
+		}
+
+		return true
+	})
+
+	return matches, nil
+}
+
+// ReplaceText replaces the non-empty matches of pattern in the text of root and returns the
+// number of replacements. The replacement of a match spanning several text nodes takes the
+// format and style of the first node, and nodes left without text are removed. Text entities,
+// such as hashtags and mentions, are not changed.
+func ReplaceText(root *RootNode, pattern, replacement string, opts FindOptions) (int, error) {
+	re, err := compileFind(pattern, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	replaced := 0
+	Walk(root, func(node lexical.Node, path Path) bool {
+		parent, ok := node.(Parent)
+		if !ok {
+			return true
+		}
+
+		children := parent.ChildNodes()
+		runs := textRuns(*children, editableText)
+		for i := len(runs) - 1; i >= 0; i-- {
+			run := runs[i]
+			nodes, n := run.replace(re, replacement, opts.Regexp)
+			if n == 0 {
+				continue
+			}
+
+			replaced += n
+			updated := make(lexical.NodeArray, 0, len(*children))
+			updated = append(updated, (*children)[:run.index]...)
+			updated = append(updated, nodes...)
+			updated = append(updated, (*children)[run.index+len(run.nodes):]...)
+			*children = updated
+		}
+
+		return true
+	})
+
+	return replaced, nil
+}
+
+func compileFind(pattern string, opts FindOptions) (*regexp.Regexp, error) {
+	if !opts.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid pattern: %v", pkg, err)
+	}
+
+	return re, nil
+}
+
+// textRuns returns the runs of adjacent text nodes of children, or nodes embedding them, for
+// which include reports true
+func textRuns(children lexical.NodeArray, include func(node lexical.Node, tn *TextNode) bool) []textRunNodes {
+	var runs []textRunNodes
+	var run *textRunNodes
+	for i, child := range children {
+		tn, ok := TextNodeOf(child)
+		if !ok || !include(child, tn) {
+			run = nil
+			continue
+		}
+
+		if run == nil {
+			runs = append(runs, textRunNodes{index: i})
+			run = &runs[len(runs)-1]
+		}
+
+		run.owners = append(run.owners, child)
+		run.nodes = append(run.nodes, tn)
+		run.text += tn.Text
+	}
+
+	return runs
+}
+
+// editableText reports whether node is text edited by FindText and ReplaceText, which is text
+// not in token mode and not a text entity
+func editableText(node lexical.Node, tn *TextNode) bool {
+	_, entity := node.(interface{ textEntity() })
+	return !entity && tn.Mode != "token"
+}
+
+// locate returns the index of the node holding byte offset of the run text and the byte
+// offset in its text. An offset between two nodes is in the second, or in the first if end.
+func (r textRunNodes) locate(offset int, end bool) (int, int) {
+	for i, tn := range r.nodes {
+		if offset < len(tn.Text) || (end && offset == len(tn.Text)) || i == len(r.nodes)-1 {
+			return i, offset
+		}
+
+		offset -= len(tn.Text)
+	}
+
+	return 0, 0
+}
+
+func (r textRunNodes) position(parent Path, offset int, end bool) Position {
+	i, local := r.locate(offset, end)
+	return Position{
+		Path:   parent.Child(r.index + i),
+		Offset: textLength(r.nodes[i].Text[:local], UTF16),
+	}
+}
+
+// replace returns the nodes of the run with the matches of re replaced and the number of
+// replacements
+func (r textRunNodes) replace(re *regexp.Regexp, replacement string, expand bool) (lexical.NodeArray, int) {
+	locs := re.FindAllStringSubmatchIndex(r.text, -1)
+
+	// texts holds the new text of every node of the run
+	texts := make([]string, len(r.nodes))
+	n, offset := 0, 0
+	keep := func(end int) {
+		for offset < end {
+			i, local := r.locate(offset, false)
+			take := min(len(r.nodes[i].Text)-local, end-offset)
+			texts[i] += r.nodes[i].Text[local : local+take]
+			offset += take
+		}
+	}
+
+	for _, loc := range locs {
+		if loc[0] == loc[1] {
+			continue
+		}
+
+		keep(loc[0])
+		i, _ := r.locate(loc[0], false)
+		if expand {
+			texts[i] += string(re.ExpandString(nil, replacement, r.text, loc))
+		} else {
+			texts[i] += replacement
+		}
+		offset = loc[1]
+		n++
+	}
+
+	if n == 0 {
+		return nil, 0
+	}
+	keep(len(r.text))
+
+	var nodes lexical.NodeArray
+	for i, owner := range r.owners {
+		if texts[i] != "" {
+			nodes = append(nodes, withText(owner, texts[i]))
+		}
+	}
+
+	return nodes, n
+}
@@ -1,13 +1,83 @@
 package nodes
 
-import "github.com/tylertravisty/go-lexical"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tylertravisty/go-lexical"
+)
 
 // RootNode is a lexical root node
 type RootNode struct {
 	Root ElementNode `json:"root"`
 }
 
+// UnmarshalJSON unmarshals the document. Documents exceeding lexical.DefaultLimits are
+// rejected with a *lexical.LimitError; use Decode to unmarshal with other limits.
+func (rn *RootNode) UnmarshalJSON(data []byte) error {
+	err := lexical.CheckLimits(data, lexical.DefaultLimits)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, (*rootJSON)(rn))
+}
+
+// rootJSON is the root node without its UnmarshalJSON method
+type rootJSON RootNode
+
 // Find finds the specified node types
 func (rn *RootNode) Find(nodes map[string][]lexical.Node) {
 	rn.Root.Find(nodes)
 }
+
+// Valid verifies the root element has type root and is valid
+func (rn *RootNode) Valid() error {
+	if rn.Root.NodeType != "root" {
+		return fmt.Errorf("%s: invalid root node: type is %q", pkg, rn.Root.NodeType)
+	}
+
+	return rn.Root.Valid()
+}
+
+// Clone returns a deep copy of the root node
+func (rn *RootNode) Clone() *RootNode {
+	root, _ := Clone(&rn.Root).(*ElementNode)
+	return &RootNode{Root: *root}
+}
+
+// Equal reports whether the root node is structurally equal to other
+func (rn *RootNode) Equal(other *RootNode, opts ...EqualOption) bool {
+	if rn == nil || other == nil {
+		return rn == nil && other == nil
+	}
+
+	return Equal(&rn.Root, &other.Root, opts...)
+}
+
+// Decode reads a document from r. Documents exceeding limits are rejected with a
+// *lexical.LimitError before they are unmarshaled.
+func Decode(r io.Reader, limits lexical.Limits) (*RootNode, error) {
+	if limits.MaxBytes > 0 {
+		r = io.LimitReader(r, int64(limits.MaxBytes)+1)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lexical.CheckLimits(data, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	var root RootNode
+	err = json.Unmarshal(data, (*rootJSON)(&root))
+	if err != nil {
+		return nil, err
+	}
+
+	return &root, nil
+}
@@ -9,6 +9,18 @@ import (
 
 var _ lexical.Node = &TextNode{}
 
+// Text format flags of the text node format bitmask
+const (
+	FormatBold = 1 << iota
+	FormatItalic
+	FormatStrikethrough
+	FormatUnderline
+	FormatCode
+	FormatSubscript
+	FormatSuperscript
+	FormatHighlight
+)
+
 // TextNode implements the lexical text node type
 type TextNode struct {
 	BaseNode
@@ -24,6 +36,24 @@ func (tn *TextNode) Find(nodes map[string][]lexical.Node) {
 	Find(tn, nodes)
 }
 
+// text returns the text node, including text nodes embedded in other node types
+func (tn *TextNode) text() *TextNode {
+	return tn
+}
+
+// TextNodeOf returns the text node of node, which is node itself or the text node embedded
+// in it, as in hashtag and mention nodes, in replacement text node types and in dynamic
+// text nodes
+func TextNodeOf(node lexical.Node) (*TextNode, bool) {
+	n, ok := node.(interface{ text() *TextNode })
+	if !ok {
+		return nil, false
+	}
+
+	tn := n.text()
+	return tn, tn != nil
+}
+
 // TextContentSize returns the length of the text
 func (tn *TextNode) TextContentSize() int {
 	return len(tn.Text)
@@ -34,6 +64,11 @@ func (tn TextNode) Type() (string, reflect.Type) {
 	return "text", reflect.TypeOf(tn)
 }
 
+// JSONType returns the type of text node, which is decoded from JSON directly
+func (tn TextNode) JSONType() reflect.Type {
+	return reflect.TypeOf(tn)
+}
+
 // Unmarshal unmarshals the text node
 func (tn *TextNode) Unmarshal(data map[string]interface{}) error {
 	tnB, err := json.Marshal(data)
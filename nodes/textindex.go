@@ -0,0 +1,217 @@
+package nodes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// BlockSeparator separates the text of blocks in the text content of a document, as in Lexical
+const BlockSeparator = "\n\n"
+
+// Unit is the unit of text offsets
+type Unit int
+
+const (
+	// UTF16 counts UTF-16 code units, as Lexical and JavaScript do
+	UTF16 Unit = iota
+	// Runes counts Unicode code points
+	Runes
+)
+
+// Position is a location in a document. When Path is the path of a text node, Offset is an
+// offset in its text; otherwise Offset is a child index of the element at Path, as in Lexical
+// element points.
+type Position struct {
+	Path   Path
+	Offset int
+}
+
+// TextIndex maps offsets in the text content of a document to positions and back.
+// The text content joins the text of blocks with BlockSeparator. An index is not
+// updated when its document changes.
+type TextIndex struct {
+	root   *RootNode
+	unit   Unit
+	text   string
+	length int
+	runs   []textRun
+	spans  map[string]textSpan
+}
+
+type textRunKind int
+
+const (
+	runText textRunKind = iota
+	runSeparator
+	runEmptyBlock
+)
+
+// textRun is a range of the text content. Separator runs hold the path of the block they follow.
+type textRun struct {
+	kind     textRunKind
+	path     Path
+	start    int
+	end      int
+	children int
+}
+
+type textSpan struct {
+	start int
+	end   int
+}
+
+// NewTextIndex returns the text index of root counting offsets in unit
+func NewTextIndex(root *RootNode, unit Unit) *TextIndex {
+	ti := &TextIndex{root: root, unit: unit, spans: map[string]textSpan{}}
+	var b strings.Builder
+	ti.index(&b, &root.Root, Path{})
+	ti.text = b.String()
+	return ti
+}
+
+func (ti *TextIndex) index(b *strings.Builder, node lexical.Node, path Path) {
+	start := ti.length
+	if tn, ok := TextNodeOf(node); ok {
+		b.WriteString(tn.Text)
+		ti.length += textLength(tn.Text, ti.unit)
+		ti.runs = append(ti.runs, textRun{kind: runText, path: path, start: start, end: ti.length})
+	}
+
+	if parent, ok := node.(Parent); ok {
+		children := *parent.ChildNodes()
+		for i, child := range children {
+			ti.index(b, child, path.Child(i))
+
+			if i < len(children)-1 && blockElement(child) {
+				childPath := path.Child(i)
+				b.WriteString(BlockSeparator)
+				ti.runs = append(ti.runs, textRun{
+					kind:     runSeparator,
+					path:     childPath,
+					start:    ti.length,
+					end:      ti.length + len(BlockSeparator),
+					children: len(*child.(Parent).ChildNodes()),
+				})
+				ti.length += len(BlockSeparator)
+			}
+		}
+
+		if ti.length == start && (len(path) == 0 || blockElement(node)) {
+			ti.runs = append(ti.runs, textRun{kind: runEmptyBlock, path: path, start: start, end: start})
+		}
+	}
+
+	ti.spans[path.key()] = textSpan{start: start, end: ti.length}
+}
+
+// blockElement reports whether node is an element that is not inline
+func blockElement(node lexical.Node) bool {
+	if _, ok := node.(Parent); !ok {
+		return false
+	}
+
+	rule, exists := DefaultContentModel[nodeType(node)]
+	return !exists || !rule.Inline
+}
+
+// textLength returns the length of s in unit
+func textLength(s string, unit Unit) int {
+	if unit == Runes {
+		return utf8.RuneCountInString(s)
+	}
+
+	n := 0
+	for _, r := range s {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Len returns the length of the text content in the unit of the index
+func (ti *TextIndex) Len() int {
+	return ti.length
+}
+
+// Text returns the text content of the document
+func (ti *TextIndex) Text() string {
+	return ti.text
+}
+
+// Unit returns the unit of the offsets of the index
+func (ti *TextIndex) Unit() Unit {
+	return ti.unit
+}
+
+// Position returns the position of offset in the text content. An offset between two text
+// nodes is at the end of the first. An offset inside a block separator is at the end of the
+// block before it.
+func (ti *TextIndex) Position(offset int) (Position, error) {
+	if offset < 0 || offset > ti.length {
+		return Position{}, fmt.Errorf("%s: offset %d out of range [0, %d]", pkg, offset, ti.length)
+	}
+
+	i := sort.Search(len(ti.runs), func(i int) bool {
+		return ti.runs[i].end >= offset
+	})
+	for ; i < len(ti.runs); i++ {
+		run := ti.runs[i]
+		switch {
+		case run.kind == runText && run.start <= offset && offset <= run.end:
+			return Position{Path: run.path, Offset: offset - run.start}, nil
+		case run.kind == runEmptyBlock && run.start == offset:
+			return Position{Path: run.path, Offset: 0}, nil
+		case run.kind == runSeparator && run.start <= offset && offset < run.end:
+			return Position{Path: run.path, Offset: run.children}, nil
+		}
+	}
+
+	return Position{}, fmt.Errorf("%s: no position at offset %d", pkg, offset)
+}
+
+// Offset returns the offset in the text content of pos
+func (ti *TextIndex) Offset(pos Position) (int, error) {
+	span, exists := ti.spans[pos.Path.key()]
+	if !exists {
+		return 0, fmt.Errorf("%s: no node at %v", pkg, pos.Path)
+	}
+
+	node, err := ti.root.NodeAt(pos.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, ok := TextNodeOf(node); ok {
+		if pos.Offset < 0 || span.start+pos.Offset > span.end {
+			return 0, fmt.Errorf("%s: offset %d out of range of text node at %v", pkg, pos.Offset, pos.Path)
+		}
+
+		return span.start + pos.Offset, nil
+	}
+
+	parent, ok := node.(Parent)
+	if !ok {
+		if pos.Offset != 0 {
+			return 0, fmt.Errorf("%s: offset %d out of range of node at %v", pkg, pos.Offset, pos.Path)
+		}
+
+		return span.start, nil
+	}
+
+	children := len(*parent.ChildNodes())
+	switch {
+	case pos.Offset < 0 || pos.Offset > children:
+		return 0, fmt.Errorf("%s: child index %d out of range of node at %v", pkg, pos.Offset, pos.Path)
+	case pos.Offset == children:
+		return span.end, nil
+	}
+
+	return ti.spans[pos.Path.Child(pos.Offset).key()].start, nil
+}
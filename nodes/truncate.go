@@ -0,0 +1,135 @@
+package nodes
+
+import (
+	"unicode"
+
+	"github.com/tylertravisty/go-lexical"
+)
+
+// DefaultEllipsis is the text appended to truncated documents by default
+const DefaultEllipsis = "…"
+
+// TruncateBy is what Truncate counts
+type TruncateBy int
+
+const (
+	// TruncateChars counts characters of the text content in the TextUnit of the options
+	TruncateChars TruncateBy = iota
+	// TruncateWords counts words of the text content
+	TruncateWords
+	// TruncateBlocks counts blocks of the root
+	TruncateBlocks
+)
+
+// TruncateOptions are the options of Truncate
+type TruncateOptions struct {
+	By TruncateBy
+	// TextUnit is the unit counting characters
+	TextUnit Unit
+	// Ellipsis is appended to truncated documents; DefaultEllipsis when empty
+	Ellipsis string
+}
+
+// Truncate returns a copy of root cut after max characters, words or blocks. Characters are
+// cut at the end of the last whole word, or mid-word when the first word is longer than max.
+// The truncated copy keeps the format of its text and the partially included elements, and
+// ends with an ellipsis text node. A document within max is returned as an unchanged copy.
+func Truncate(root *RootNode, max int, opts TruncateOptions) *RootNode {
+	if max < 0 {
+		max = 0
+	}
+
+	index := NewTextIndex(root, opts.TextUnit)
+	var end Position
+	switch opts.By {
+	case TruncateBlocks:
+		if len(root.Root.Children) <= max {
+			return root.Clone()
+		}
+
+		end = Position{Path: Path{}, Offset: max}
+	default:
+		offset, cut := truncateOffset(index.Text(), max, opts)
+		if !cut {
+			return root.Clone()
+		}
+
+		var err error
+		end, err = index.Position(offset)
+		if err != nil {
+			return root.Clone()
+		}
+	}
+
+	truncated, err := index.Extract(Position{Path: Path{}, Offset: 0}, end)
+	if err != nil {
+		return root.Clone()
+	}
+
+	ellipsis := opts.Ellipsis
+	if ellipsis == "" {
+		ellipsis = DefaultEllipsis
+	}
+
+	appendEllipsis(truncated, ellipsis)
+	return truncated
+}
+
+// truncateOffset returns the offset in the unit of opts at which text is cut, and whether it is cut
+func truncateOffset(text string, max int, opts TruncateOptions) (int, bool) {
+	offset, words := 0, 0
+	wordEnd, inWord := -1, false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			if inWord {
+				wordEnd = offset
+			}
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			words++
+			if opts.By == TruncateWords && words > max {
+				return max0(wordEnd), true
+			}
+		}
+
+		if opts.By == TruncateChars && offset >= max {
+			if wordEnd > 0 || !inWord {
+				return max0(wordEnd), true
+			}
+
+			return offset, true
+		}
+
+		offset += textLength(string(r), opts.TextUnit)
+	}
+
+	return offset, false
+}
+
+func max0(n int) int {
+	if n < 0 {
+		return 0
+	}
+
+	return n
+}
+
+// appendEllipsis appends a text node holding ellipsis to the last block of root
+// holding inline content, or in a new paragraph
+func appendEllipsis(root *RootNode, ellipsis string) {
+	tn := &TextNode{BaseNode: BaseNode{NodeType: "text", Version: 1}, Mode: "normal", Text: ellipsis}
+	children := root.Root.Children
+	if n := len(children); n > 0 && inlineContainer(children[n-1]) {
+		last := children[n-1].(Parent).ChildNodes()
+		*last = append(*last, tn)
+		return
+	}
+
+	root.Root.Children = append(children, &ParagraphNode{
+		ElementNode: ElementNode{
+			BaseNode: BaseNode{NodeType: "paragraph", Version: 1},
+			Children: lexical.NodeArray{tn},
+		},
+	})
+}
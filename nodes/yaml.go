@@ -0,0 +1,413 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a line of a YAML document without its indentation and comment
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+// yamlParser parses the subset of YAML used by node specs: block mappings and sequences,
+// flow mappings and sequences, and plain, single-quoted and double-quoted scalars. Anchors,
+// aliases, tags, multi-line scalars and multiple documents are not supported.
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+// yamlToJSON converts a YAML document to JSON
+func yamlToJSON(data []byte) ([]byte, error) {
+	p := &yamlParser{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(stripYAMLComment(line), " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || (i == 0 && text == "---") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tab indentation", i+1)
+		}
+
+		p.lines = append(p.lines, yamlLine{indent: len(line) - len(text), text: text, number: i + 1})
+	}
+
+	var value any
+	if len(p.lines) > 0 {
+		var err error
+		value, err = p.block(p.lines[0].indent)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.i < len(p.lines) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.i].number)
+		}
+	}
+
+	return json.Marshal(value)
+}
+
+// stripYAMLComment removes the comment ending line
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// block parses the block node starting at the current line, indented by indent
+func (p *yamlParser) block(indent int) (any, error) {
+	line := p.lines[p.i]
+	switch {
+	case yamlSequenceItem(line.text):
+		return p.sequence(indent)
+	case yamlKeyEnd(line.text) >= 0:
+		return p.mapping(indent)
+	}
+
+	p.i++
+	return yamlScalar(line.text, line.number)
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	items := []any{}
+	// A sequence indented as the key of its mapping ends at the next key
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent && yamlSequenceItem(p.lines[p.i].text) {
+		line := p.lines[p.i]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" {
+			value, err := p.nested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+			continue
+		}
+
+		// The item content is parsed as a block starting after the dash, so that the
+		// following lines of a mapping item align with its first key
+		p.lines[p.i] = yamlLine{indent: indent + len(line.text) - len(rest), text: rest, number: line.number}
+		value, err := p.block(p.lines[p.i].indent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+
+	return items, p.checkIndent(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	object := map[string]any{}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent {
+		line := p.lines[p.i]
+		end := yamlKeyEnd(line.text)
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: expected mapping key", line.number)
+		}
+
+		key, err := yamlKey(line.text[:end], line.number)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, exists := object[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+
+		rest := strings.TrimLeft(line.text[end+1:], " ")
+		if rest == "" {
+			object[key], err = p.nested(indent, true)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		p.i++
+		object[key], err = yamlScalar(rest, line.number)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return object, p.checkIndent(indent)
+}
+
+// nested parses the block node following the line ending with a key or a dash, which is
+// null when the next line is not indented further. Sequences of mapping values may be
+// indented as their key.
+func (p *yamlParser) nested(indent int, key bool) (any, error) {
+	p.i++
+	if p.i >= len(p.lines) {
+		return nil, nil
+	}
+
+	next := p.lines[p.i]
+	if next.indent > indent || (key && next.indent == indent && yamlSequenceItem(next.text)) {
+		return p.block(next.indent)
+	}
+
+	return nil, nil
+}
+
+// checkIndent verifies the block ending at the current line is not followed by a line
+// indented further
+func (p *yamlParser) checkIndent(indent int) error {
+	if p.i < len(p.lines) && p.lines[p.i].indent > indent {
+		return fmt.Errorf("line %d: unexpected indentation", p.lines[p.i].number)
+	}
+
+	return nil
+}
+
+func yamlSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// yamlKeyEnd returns the index of the colon ending the mapping key of text, or -1
+func yamlKeyEnd(text string) int {
+	if text == "" || strings.ContainsRune("[{", rune(text[0])) {
+		return -1
+	}
+
+	i := 0
+	if text[0] == '"' || text[0] == '\'' {
+		i = yamlQuotedEnd(text)
+		if i < 0 {
+			return -1
+		}
+	}
+
+	for ; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// yamlQuotedEnd returns the index after the quoted scalar starting text, or -1
+func yamlQuotedEnd(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i + 1
+		}
+	}
+
+	return -1
+}
+
+func yamlKey(text string, number int) (string, error) {
+	value, err := yamlScalar(strings.TrimSpace(text), number)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	return "", fmt.Errorf("line %d: invalid mapping key %q", number, text)
+}
+
+// yamlScalar parses the value of a single line, which is a scalar or a flow collection
+func yamlScalar(text string, number int) (any, error) {
+	f := &yamlFlow{text: text}
+	value, err := f.value(false)
+	if err == nil && f.skipSpace() < len(text) {
+		err = fmt.Errorf("unexpected %q", text[f.i:])
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %v", number, err)
+	}
+
+	return value, nil
+}
+
+// yamlFlow parses scalars and flow collections
+type yamlFlow struct {
+	text string
+	i    int
+}
+
+func (f *yamlFlow) skipSpace() int {
+	for f.i < len(f.text) && (f.text[f.i] == ' ' || f.text[f.i] == '\t') {
+		f.i++
+	}
+
+	return f.i
+}
+
+// value parses the value at the current offset. In flow collections plain scalars end at
+// flow indicators.
+func (f *yamlFlow) value(inFlow bool) (any, error) {
+	if f.skipSpace() >= len(f.text) {
+		return nil, nil
+	}
+
+	switch c := f.text[f.i]; c {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		end := yamlQuotedEnd(f.text[f.i:])
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+
+		quoted := f.text[f.i : f.i+end]
+		f.i += end
+		if c == '\'' {
+			return strings.ReplaceAll(quoted[1:len(quoted)-1], "''", "'"), nil
+		}
+
+		var s string
+		err := json.Unmarshal([]byte(quoted), &s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", quoted)
+		}
+
+		return s, nil
+	case '&', '*', '!', '|', '>', '%', '@', '`':
+		return nil, fmt.Errorf("unsupported %q", f.text[f.i:])
+	}
+
+	start := f.i
+	for f.i < len(f.text) {
+		c := f.text[f.i]
+		if inFlow && (c == ',' || c == ']' || c == '}' || (c == ':' && (f.i+1 == len(f.text) || f.text[f.i+1] == ' '))) {
+			break
+		}
+		f.i++
+	}
+
+	return yamlPlain(strings.TrimSpace(f.text[start:f.i])), nil
+}
+
+func (f *yamlFlow) sequence() (any, error) {
+	f.i++
+	items := []any{}
+	for {
+		if f.skipSpace() < len(f.text) && f.text[f.i] == ']' {
+			f.i++
+			return items, nil
+		}
+
+		value, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+
+		if !f.separator(']') {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+	}
+}
+
+func (f *yamlFlow) mapping() (any, error) {
+	f.i++
+	object := map[string]any{}
+	for {
+		if f.skipSpace() < len(f.text) && f.text[f.i] == '}' {
+			f.i++
+			return object, nil
+		}
+
+		key, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid flow mapping key")
+		}
+
+		if f.skipSpace() >= len(f.text) || f.text[f.i] != ':' {
+			return nil, fmt.Errorf("expected colon after key %q", name)
+		}
+		f.i++
+
+		object[name], err = f.value(true)
+		if err != nil {
+			return nil, err
+		}
+
+		if !f.separator('}') {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+	}
+}
+
+// separator skips the comma after a flow collection entry, and reports whether the entry
+// is followed by a comma or by the closing indicator
+func (f *yamlFlow) separator(closing byte) bool {
+	if f.skipSpace() >= len(f.text) {
+		return false
+	}
+
+	switch f.text[f.i] {
+	case ',':
+		f.i++
+		return true
+	case closing:
+		return true
+	}
+
+	return false
+}
+
+// yamlPlain returns the value of a plain scalar
+func yamlPlain(text string) any {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+
+	if _, err := strconv.ParseFloat(text, 64); err == nil && json.Valid([]byte(text)) {
+		return json.Number(text)
+	}
+
+	return text
+}
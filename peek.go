@@ -0,0 +1,358 @@
+package lexical
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// peekField returns the raw value of a top level field of a JSON object without
+// decoding the rest of the object
+func peekField(data []byte, name string) ([]byte, bool, error) {
+	i := skipSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return nil, false, fmt.Errorf("expected object")
+	}
+	i++
+
+	for {
+		i = skipSpace(data, i)
+		if i < len(data) && data[i] == '}' {
+			return nil, false, nil
+		}
+
+		keyStart := i
+		i = skipString(data, i)
+		if i < 0 {
+			return nil, false, fmt.Errorf("invalid object key")
+		}
+		key := data[keyStart:i]
+
+		i = skipSpace(data, i)
+		if i >= len(data) || data[i] != ':' {
+			return nil, false, fmt.Errorf("expected colon")
+		}
+
+		valueStart := skipSpace(data, i+1)
+		i = skipValue(data, valueStart)
+		if i < 0 {
+			return nil, false, fmt.Errorf("invalid value")
+		}
+
+		if keyEquals(key, name) {
+			return data[valueStart:i], true, nil
+		}
+
+		i = skipSpace(data, i)
+		if i >= len(data) {
+			return nil, false, fmt.Errorf("unexpected end of object")
+		}
+
+		switch data[i] {
+		case ',':
+			i++
+		case '}':
+			return nil, false, nil
+		default:
+			return nil, false, fmt.Errorf("expected comma")
+		}
+	}
+}
+
+// keyEquals compares a quoted JSON key with name as encoding/json matches keys with struct
+// fields: after unescaping and without regard to case
+func keyEquals(key []byte, name string) bool {
+	if len(key) == len(name)+2 && strings.EqualFold(string(key[1:len(key)-1]), name) {
+		return true
+	}
+
+	if bytes.IndexByte(key, '\\') < 0 {
+		return false
+	}
+
+	var unquoted string
+	return json.Unmarshal(key, &unquoted) == nil && strings.EqualFold(unquoted, name)
+}
+
+// decodeNodes decodes the JSON array of nodes starting at i and returns the index after it
+func decodeNodes(data []byte, i int) (NodeArray, int, error) {
+	i = skipSpace(data, i)
+	if i >= len(data) || data[i] != '[' {
+		return nil, 0, fmt.Errorf("expected array")
+	}
+
+	nodes := NodeArray{}
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == ']' {
+		return nodes, i + 1, nil
+	}
+
+	for {
+		node, end, err := decodeNode(data, i)
+		if err != nil {
+			return nil, 0, err
+		}
+		nodes = append(nodes, node)
+
+		i = skipSpace(data, end)
+		if i >= len(data) {
+			return nil, 0, fmt.Errorf("unexpected end of array")
+		}
+
+		switch data[i] {
+		case ',':
+			i++
+		case ']':
+			return nodes, i + 1, nil
+		default:
+			return nil, 0, fmt.Errorf("expected comma")
+		}
+	}
+}
+
+// decodeNode decodes the node starting at i and returns the index after it. The children of
+// the node are decoded while its fields are scanned, since Lexical writes them before the type,
+// and the other fields are then decoded into a node of the registered type. Every byte of a
+// document is so scanned once, whatever its depth. Nodes that do not hold their children in
+// a NodeArray, or that are decoded by their Unmarshal method, are decoded from the whole object.
+func decodeNode(data []byte, i int) (Node, int, error) {
+	start := skipSpace(data, i)
+	if start >= len(data) || data[start] != '{' {
+		return nil, 0, fmt.Errorf("expected object")
+	}
+
+	fields := []byte{'{'}
+	var children NodeArray
+	var childrenErr error
+	var rawType []byte
+	hasChildren := false
+	i = skipSpace(data, start+1)
+	empty := i < len(data) && data[i] == '}'
+	if empty {
+		i++
+	}
+
+	for !empty {
+		i = skipSpace(data, i)
+		keyStart := i
+		i = skipString(data, i)
+		if i < 0 {
+			return nil, 0, fmt.Errorf("invalid object key")
+		}
+		key := data[keyStart:i]
+
+		i = skipSpace(data, i)
+		if i >= len(data) || data[i] != ':' {
+			return nil, 0, fmt.Errorf("expected colon")
+		}
+
+		valueStart := skipSpace(data, i+1)
+		if keyEquals(key, "children") && valueStart < len(data) && data[valueStart] == '[' {
+			// The type is not known yet, so children that are not nodes are kept for the
+			// node to decode
+			hasChildren = true
+			children, i, childrenErr = decodeNodes(data, valueStart)
+			if childrenErr != nil {
+				i = skipValue(data, valueStart)
+				if i < 0 {
+					return nil, 0, fmt.Errorf("invalid value")
+				}
+			}
+		} else {
+			i = skipValue(data, valueStart)
+			if i < 0 {
+				return nil, 0, fmt.Errorf("invalid value")
+			}
+
+			if keyEquals(key, "type") {
+				rawType = data[valueStart:i]
+			}
+
+			if len(fields) > 1 {
+				fields = append(fields, ',')
+			}
+			fields = append(fields, data[keyStart:i]...)
+		}
+
+		i = skipSpace(data, i)
+		if i >= len(data) {
+			return nil, 0, fmt.Errorf("unexpected end of object")
+		}
+
+		if data[i] == '}' {
+			i++
+			break
+		}
+
+		if data[i] != ',' {
+			return nil, 0, fmt.Errorf("expected comma")
+		}
+		i++
+	}
+	fields = append(fields, '}')
+
+	var nodeTypeName string
+	if rawType == nil || json.Unmarshal(rawType, &nodeTypeName) != nil {
+		return nil, 0, fmt.Errorf("%s: invalid node type", pkg)
+	}
+
+	if _, exists := DefaultNodeTypes.Type(nodeTypeName); !exists {
+		return nil, 0, fmt.Errorf("%s: unsupported node type", pkg)
+	}
+
+	node, ok := DefaultNodeTypes.New(nodeTypeName)
+	if !ok {
+		return nil, 0, fmt.Errorf("%s: invalid node", pkg)
+	}
+
+	if !decodesJSON(node) {
+		var object map[string]any
+		err := json.Unmarshal(data[start:i], &object)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: error unmarshaling node: %v", pkg, err)
+		}
+
+		object, err = migrateMap(nodeTypeName, node, object)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		err = node.Unmarshal(object)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: error unmarshaling node: %v", pkg, err)
+		}
+
+		return node, i, nil
+	}
+
+	parent, isParent := node.(interface{ ChildNodes() *NodeArray })
+	if hasChildren && isParent && childrenErr != nil {
+		return nil, 0, childrenErr
+	}
+
+	migrateNode, err := needsMigrationJSON(nodeTypeName, node, fields)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Nodes that do not expose their children and nodes to migrate, whose migrations see their
+	// children, are decoded from the whole object
+	if migrateNode || hasChildren && !isParent {
+		fields = data[start:i]
+		hasChildren = false
+	}
+
+	if migrateNode {
+		fields, err = migrateJSON(nodeTypeName, node, fields)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	err = json.Unmarshal(fields, node)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: error unmarshaling node: %v", pkg, err)
+	}
+
+	if hasChildren && isParent {
+		*parent.ChildNodes() = children
+	}
+
+	return node, i, nil
+}
+
+// decodesJSON reports whether node is decoded from JSON directly rather than by its
+// Unmarshal method
+func decodesJSON(node Node) bool {
+	jn, ok := node.(JSONNode)
+	if !ok {
+		return false
+	}
+
+	nodeType := reflect.TypeOf(node)
+	if nodeType.Kind() == reflect.Pointer {
+		nodeType = nodeType.Elem()
+	}
+
+	return jn.JSONType() == nodeType
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+// skipString returns the index after the string starting at i, or -1
+func skipString(data []byte, i int) int {
+	if i >= len(data) || data[i] != '"' {
+		return -1
+	}
+
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+
+	return -1
+}
+
+// skipValue returns the index after the value starting at i, or -1
+func skipValue(data []byte, i int) int {
+	if i >= len(data) {
+		return -1
+	}
+
+	switch data[i] {
+	case '"':
+		return skipString(data, i)
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				i = skipString(data, i)
+				if i < 0 {
+					return -1
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+		return -1
+	}
+
+	start := i
+	for i < len(data) {
+		switch data[i] {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			if i == start {
+				return -1
+			}
+			return i
+		}
+		i++
+	}
+
+	return i
+}
@@ -0,0 +1,214 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+)
+
+// Detector finds personal data in text
+type Detector interface {
+	// Name names the kind of data found, as reported in findings
+	Name() string
+	// Detect returns the byte ranges of the data found in text
+	Detect(text string) [][]int
+}
+
+var (
+	emailRegexp      = regexp.MustCompile(`[A-Za-z0-9._%+-]+(?:@|%40)[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	phoneRegexp      = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){1,4}`)
+	creditCardRegexp = regexp.MustCompile(`\d(?:[ -]?\d){12,18}`)
+	dateRegexp       = regexp.MustCompile(`^(?:\d{4}[-./]\d{1,2}[-./]\d{1,2}|\d{1,2}[-./]\d{1,2}[-./]\d{2,4})(?:\s|$)`)
+)
+
+// DefaultDetectors returns the email, credit card and phone detectors. Credit cards come
+// before phones so that card numbers, which are also shaped as phone numbers, are reported
+// as cards.
+func DefaultDetectors() []Detector {
+	return []Detector{Email(), CreditCard(), Phone()}
+}
+
+// Email returns a detector of email addresses, including addresses percent-encoded in
+// mailto: links
+func Email() Detector {
+	return Regexp("email", emailRegexp)
+}
+
+// Phone returns a detector of phone numbers of 7 to 15 digits, optionally with a country
+// code and separated by spaces, dots, dashes or parentheses. Numbers without a country code
+// have 10 to 12 digits, or at least 7 with an area code in parentheses, and dates are not
+// numbers.
+func Phone() Detector {
+	return &regexpDetector{name: "phone", re: phoneRegexp, valid: func(match string) bool {
+		n := len(digits(match))
+		if n < 7 || n > 15 || dateRegexp.MatchString(match) {
+			return false
+		}
+
+		if match[0] == '+' {
+			return true
+		}
+
+		return n <= 12 && (match[0] == '(' || n >= 10)
+	}}
+}
+
+// CreditCard returns a detector of payment card numbers of 13 to 19 digits, optionally
+// separated by spaces or dashes, passing the Luhn check
+func CreditCard() Detector {
+	return &regexpDetector{name: "credit_card", re: creditCardRegexp, valid: func(match string) bool {
+		return luhn(digits(match))
+	}}
+}
+
+// Regexp returns a detector of the matches of re
+func Regexp(name string, re *regexp.Regexp) Detector {
+	return &regexpDetector{name: name, re: re}
+}
+
+// regexpDetector finds the matches of re. Matches of detectors with a valid function that
+// it rejects are shortened to the longest valid prefix ending with a digit before a
+// separator, so that data followed by other digits, as a card number followed by its
+// security code, is found, and the text after the prefix is searched again.
+type regexpDetector struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+func (d *regexpDetector) Name() string {
+	return d.name
+}
+
+func (d *regexpDetector) Detect(text string) [][]int {
+	var ranges [][]int
+	if d.valid == nil {
+		for _, loc := range d.re.FindAllStringIndex(text, -1) {
+			if loc[0] != loc[1] && wordBoundary(text, loc[0], loc[1]) {
+				ranges = append(ranges, loc)
+			}
+		}
+
+		return ranges
+	}
+
+	for offset := 0; offset < len(text); {
+		loc := d.re.FindStringIndex(text[offset:])
+		if loc == nil {
+			break
+		}
+
+		start, end := offset+loc[0], offset+loc[1]
+		if start == end {
+			offset = end + 1
+			continue
+		}
+
+		offset = end
+		if valid, ok := d.longestValid(text, start, end); ok {
+			ranges = append(ranges, []int{start, valid})
+			offset = valid
+		}
+	}
+
+	return ranges
+}
+
+// longestValid returns the end of the longest valid range of text starting at start and
+// ending at end or at a digit before a separator
+func (d *regexpDetector) longestValid(text string, start, end int) (int, bool) {
+	for i := end; i > start; i-- {
+		if i < end && !(isDigit(text[i-1]) && !isDigit(text[i])) {
+			continue
+		}
+
+		if wordBoundary(text, start, i) && d.valid(text[start:i]) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// wordBoundary reports whether the range of text does not start or end inside a run of
+// ASCII letters and digits
+func wordBoundary(text string, start, end int) bool {
+	return (start == 0 || !alnum(text[start-1]) || !alnum(text[start])) &&
+		(end == len(text) || !alnum(text[end]) || !alnum(text[end-1]))
+}
+
+func alnum(b byte) bool {
+	return isDigit(b) || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func digits(s string) []byte {
+	var ds []byte
+	for i := 0; i < len(s); i++ {
+		if isDigit(s[i]) {
+			ds = append(ds, s[i])
+		}
+	}
+
+	return ds
+}
+
+// luhn reports whether ds is a card number of 13 to 19 digits with a valid Luhn check digit
+func luhn(ds []byte) bool {
+	if len(ds) < 13 || len(ds) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := range ds {
+		d := int(ds[len(ds)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	return sum%10 == 0
+}
+
+// match is data found by a detector
+type match struct {
+	detector string
+	start    int
+	end      int
+}
+
+// detect returns the non-overlapping matches of detectors in text in order. Of overlapping
+// matches the first starting is kept, then the longest, then the one of the first detector.
+func detect(text string, detectors []Detector) []match {
+	var found []match
+	for _, d := range detectors {
+		for _, loc := range d.Detect(text) {
+			found = append(found, match{detector: d.Name(), start: loc[0], end: loc[1]})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].start != found[j].start {
+			return found[i].start < found[j].start
+		}
+
+		return found[i].end > found[j].end
+	})
+
+	var matches []match
+	for _, m := range found {
+		if len(matches) > 0 && m.start < matches[len(matches)-1].end {
+			continue
+		}
+
+		matches = append(matches, m)
+	}
+
+	return matches
+}
@@ -0,0 +1,300 @@
+// Package redact removes personal data from lexical documents.
+package redact
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tylertravisty/go-lexical"
+	"github.com/tylertravisty/go-lexical/nodes"
+)
+
+// DefaultMask is the rune masking redacted characters by default
+const DefaultMask = '*'
+
+// Mode is how data is redacted
+type Mode int
+
+const (
+	// Mask replaces every character of the data with the mask of the options
+	Mask Mode = iota
+	// Remove deletes the data, except data whose removal would leave a node invalid, which is
+	// masked: the text of nodes embedding text nodes, such as hashtags and mentions, mention
+	// names and the text of links it would leave without text
+	Remove
+)
+
+// Field is the part of a node data was found in
+type Field string
+
+const (
+	FieldText        Field = "text"
+	FieldURL         Field = "url"
+	FieldTitle       Field = "title"
+	FieldMentionName Field = "mentionName"
+)
+
+// Options are the options of Redact
+type Options struct {
+	// Detectors find the data to redact; DefaultDetectors when nil
+	Detectors []Detector
+	Mode      Mode
+	// Mask masks redacted characters; DefaultMask when zero
+	Mask rune
+}
+
+// Finding is redacted data. It holds where the data was found but never the data itself.
+type Finding struct {
+	// Detector is the name of the detector that found the data
+	Detector string
+	Field    Field
+	// Start and End are the positions of the data before redaction, with offsets in UTF-16
+	// code units. Offsets in a url, title or mention name are offsets in that field of the
+	// node at Path.
+	Start nodes.Position
+	End   nodes.Position
+}
+
+// Redact redacts the data found by the detectors of opts in the text nodes of root, including
+// hashtags and mentions, in the url and title of its links and in the names of its mentions,
+// and returns the findings in document order. Data is found across
+// adjacent text nodes, so that formatting does not hide it. The structure of root is kept:
+// text nodes left empty are not removed, and the paths of findings stay valid.
+func Redact(root *nodes.RootNode, opts Options) []Finding {
+	if opts.Detectors == nil {
+		opts.Detectors = DefaultDetectors()
+	}
+
+	if opts.Mask == 0 {
+		opts.Mask = DefaultMask
+	}
+
+	// mask is opts masking data, for data whose removal would leave a node invalid
+	mask := opts
+	mask.Mode = Mask
+
+	var findings []Finding
+	// maskedLink is the path of the link whose text is masked instead of removed, or nil
+	var maskedLink nodes.Path
+	nodes.Walk(root, func(node lexical.Node, path nodes.Path) bool {
+		if maskedLink != nil && !within(path, maskedLink) {
+			maskedLink = nil
+		}
+
+		var link *nodes.LinkNode
+		switch n := node.(type) {
+		case *nodes.LinkNode:
+			link = n
+		case *nodes.AutoLinkNode:
+			link = &n.LinkNode
+		}
+
+		if link != nil {
+			findings = append(findings, redactField(&link.URL, FieldURL, path, opts)...)
+			if link.Title != nil {
+				findings = append(findings, redactField(link.Title, FieldTitle, path, opts)...)
+			}
+
+			if maskedLink == nil && opts.Mode == Remove && remainingText(node, opts) == 0 {
+				maskedLink = path
+			}
+		}
+
+		if mention, ok := node.(*nodes.MentionNode); ok {
+			findings = append(findings, redactField(&mention.MentionName, FieldMentionName, path, mask)...)
+		}
+
+		if parent, ok := node.(nodes.Parent); ok {
+			textOpts := opts
+			if maskedLink != nil {
+				textOpts = mask
+			}
+
+			for _, run := range textRuns(*parent.ChildNodes()) {
+				findings = append(findings, run.redact(path, textOpts)...)
+			}
+		}
+
+		return true
+	})
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Start.Path.Compare(findings[j].Start.Path) < 0
+	})
+
+	return findings
+}
+
+func redactField(value *string, field Field, path nodes.Path, opts Options) []Finding {
+	matches := detect(*value, opts.Detectors)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	findings := make([]Finding, 0, len(matches))
+	for _, m := range matches {
+		findings = append(findings, Finding{
+			Detector: m.detector,
+			Field:    field,
+			Start:    nodes.Position{Path: path, Offset: utf16Len((*value)[:m.start])},
+			End:      nodes.Position{Path: path, Offset: utf16Len((*value)[:m.end])},
+		})
+	}
+
+	*value = redactText(*value, 0, matches, opts)
+	return findings
+}
+
+// within reports whether path is prefix or a descendant of it
+func within(path, prefix nodes.Path) bool {
+	return len(path) >= len(prefix) && slices.Equal(path[:len(prefix)], prefix)
+}
+
+// remainingText returns the length of the text of the descendants of node left by redacting
+// it with opts
+func remainingText(node lexical.Node, opts Options) int {
+	parent, ok := node.(nodes.Parent)
+	if !ok {
+		return 0
+	}
+
+	n := 0
+	for _, run := range textRuns(*parent.ChildNodes()) {
+		for _, text := range run.redacted(detect(run.text, opts.Detectors), opts) {
+			n += len(text)
+		}
+	}
+
+	for _, child := range *parent.ChildNodes() {
+		if _, ok := nodes.TextNodeOf(child); !ok {
+			n += remainingText(child, opts)
+		}
+	}
+
+	return n
+}
+
+// textRun is a run of adjacent sibling text nodes
+type textRun struct {
+	// owners are the nodes of the run, which are or embed its text nodes
+	owners lexical.NodeArray
+	nodes  []*nodes.TextNode
+	// index is the index of the first node in its parent
+	index int
+	text  string
+}
+
+func textRuns(children lexical.NodeArray) []textRun {
+	var runs []textRun
+	var run *textRun
+	for i, child := range children {
+		tn, ok := nodes.TextNodeOf(child)
+		if !ok {
+			run = nil
+			continue
+		}
+
+		if run == nil {
+			runs = append(runs, textRun{index: i})
+			run = &runs[len(runs)-1]
+		}
+
+		run.owners = append(run.owners, child)
+		run.nodes = append(run.nodes, tn)
+		run.text += tn.Text
+	}
+
+	return runs
+}
+
+func (r textRun) redact(parent nodes.Path, opts Options) []Finding {
+	matches := detect(r.text, opts.Detectors)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	findings := make([]Finding, 0, len(matches))
+	for _, m := range matches {
+		findings = append(findings, Finding{
+			Detector: m.detector,
+			Field:    FieldText,
+			Start:    r.position(parent, m.start, false),
+			End:      r.position(parent, m.end, true),
+		})
+	}
+
+	for i, text := range r.redacted(matches, opts) {
+		r.nodes[i].Text = text
+	}
+
+	return findings
+}
+
+// redacted returns the texts of the nodes of the run with matches redacted. The text of nodes
+// embedding text nodes is masked in Remove mode.
+func (r textRun) redacted(matches []match, opts Options) []string {
+	texts := make([]string, len(r.nodes))
+	offset := 0
+	for i, tn := range r.nodes {
+		nodeOpts := opts
+		if _, plain := r.owners[i].(*nodes.TextNode); !plain {
+			nodeOpts.Mode = Mask
+		}
+
+		texts[i] = redactText(tn.Text, offset, matches, nodeOpts)
+		offset += len(tn.Text)
+	}
+
+	return texts
+}
+
+// position returns the position of byte offset of the run text. An offset between two nodes
+// is in the second, or in the first if end.
+func (r textRun) position(parent nodes.Path, offset int, end bool) nodes.Position {
+	for i, tn := range r.nodes {
+		if offset < len(tn.Text) || (end && offset == len(tn.Text)) || i == len(r.nodes)-1 {
+			return nodes.Position{Path: parent.Child(r.index + i), Offset: utf16Len(tn.Text[:min(offset, len(tn.Text))])}
+		}
+
+		offset -= len(tn.Text)
+	}
+
+	return nodes.Position{}
+}
+
+// redactText returns s, found at byte offset of the text matches were found in, with the
+// matches masked or removed
+func redactText(s string, offset int, matches []match, opts Options) string {
+	var b strings.Builder
+	pos := 0
+	for _, m := range matches {
+		start := min(max(m.start-offset, pos), len(s))
+		end := min(max(m.end-offset, start), len(s))
+		if start == end {
+			continue
+		}
+
+		b.WriteString(s[pos:start])
+		if opts.Mode == Mask {
+			b.WriteString(strings.Repeat(string(opts.Mask), utf8.RuneCountInString(s[start:end])))
+		}
+		pos = end
+	}
+	b.WriteString(s[pos:])
+
+	return b.String()
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+
+	return n
+}
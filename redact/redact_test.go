@@ -0,0 +1,154 @@
+package redact
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tylertravisty/go-lexical"
+	"github.com/tylertravisty/go-lexical/nodes"
+)
+
+func document(t *testing.T, paragraphs ...string) *nodes.RootNode {
+	var blocks []string
+	for _, paragraph := range paragraphs {
+		blocks = append(blocks, `{"children":[`+paragraph+`],"type":"paragraph","version":1}`)
+	}
+
+	var root nodes.RootNode
+	err := json.Unmarshal([]byte(`{"root":{"children":[`+strings.Join(blocks, ",")+`],"type":"root","version":1}}`), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	return &root
+}
+
+func TestDetectors(t *testing.T) {
+	tests := []struct {
+		detector Detector
+		text     string
+		expected []string
+	}{
+		{Email(), "mail ann.lee+x@example.co.uk or bob%40example.com", []string{"ann.lee+x@example.co.uk", "bob%40example.com"}},
+		{Phone(), "call +1 (555) 123-4567, (555) 123-4567 or 555.123.4567, not 555.1234 or 12-34", []string{"+1 (555) 123-4567", "(555) 123-4567", "555.123.4567"}},
+		{Phone(), "dates 2024-01-15, 2024-01-15 10:30, 15.01.2024 10:30 and 01/15/2024", nil},
+		{CreditCard(), "card 4111 1111 1111 1111, not 4111 1111 1111 1112", []string{"4111 1111 1111 1111"}},
+		{CreditCard(), "card 4111 1111 1111 1111 123", []string{"4111 1111 1111 1111"}},
+		{Phone(), "call 555 123 4567 555 987 6543", []string{"555 123 4567", "555 987 6543"}},
+		{Regexp("ssn", regexp.MustCompile(`\d{3}-\d{2}-\d{4}`)), "ssn 078-05-1120", []string{"078-05-1120"}},
+	}
+
+	for _, test := range tests {
+		var found []string
+		for _, loc := range test.detector.Detect(test.text) {
+			found = append(found, test.text[loc[0]:loc[1]])
+		}
+
+		if !reflect.DeepEqual(found, test.expected) {
+			t.Fatalf("expected %s matches %q; got %q", test.detector.Name(), test.expected, found)
+		}
+	}
+
+	matches := detect("amex 378282246310005", DefaultDetectors())
+	if len(matches) != 1 || matches[0].detector != "credit_card" {
+		t.Fatalf("expected credit card match; got %v", matches)
+	}
+
+	matches = detect("card 4111 1111 1111 1111 123", DefaultDetectors())
+	if len(matches) != 1 || matches[0].detector != "credit_card" || matches[0].end != 24 {
+		t.Fatalf("expected credit card match without security code; got %v", matches)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&nodes.ParagraphNode{}, &nodes.TextNode{}, &nodes.LinkNode{})
+
+	data := func() *nodes.RootNode {
+		return document(t,
+			`{"text":"Write to ann","type":"text","version":1},{"format":1,"text":"@example.com","type":"text","version":1},{"text":" today","type":"text","version":1}`,
+			`{"children":[{"text":"Ann","type":"text","version":1}],"title":"Mail ann@example.com","type":"link","url":"mailto:ann%40example.com?subject=Hi","version":1},{"text":" card 4111-1111-1111-1111","type":"text","version":1}`,
+		)
+	}
+
+	root := data()
+	findings := Redact(root, Options{})
+
+	expected := []Finding{
+		{Detector: "email", Field: FieldText, Start: nodes.Position{Path: nodes.Path{0, 0}, Offset: 9}, End: nodes.Position{Path: nodes.Path{0, 1}, Offset: 12}},
+		{Detector: "email", Field: FieldURL, Start: nodes.Position{Path: nodes.Path{1, 0}, Offset: 7}, End: nodes.Position{Path: nodes.Path{1, 0}, Offset: 24}},
+		{Detector: "email", Field: FieldTitle, Start: nodes.Position{Path: nodes.Path{1, 0}, Offset: 5}, End: nodes.Position{Path: nodes.Path{1, 0}, Offset: 20}},
+		{Detector: "credit_card", Field: FieldText, Start: nodes.Position{Path: nodes.Path{1, 1}, Offset: 6}, End: nodes.Position{Path: nodes.Path{1, 1}, Offset: 25}},
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Fatalf("expected findings %v; got %v", expected, findings)
+	}
+
+	masked := document(t,
+		`{"text":"Write to ***","type":"text","version":1},{"format":1,"text":"************","type":"text","version":1},{"text":" today","type":"text","version":1}`,
+		`{"children":[{"text":"Ann","type":"text","version":1}],"title":"Mail ***************","type":"link","url":"mailto:*****************?subject=Hi","version":1},{"text":" card *******************","type":"text","version":1}`,
+	)
+	if !root.Equal(masked) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected masked document; got %s", rootB)
+	}
+
+	root = data()
+	Redact(root, Options{Detectors: []Detector{Email()}, Mode: Remove})
+
+	removed := document(t,
+		`{"text":"Write to ","type":"text","version":1},{"format":1,"text":"","type":"text","version":1},{"text":" today","type":"text","version":1}`,
+		`{"children":[{"text":"Ann","type":"text","version":1}],"title":"Mail ","type":"link","url":"mailto:?subject=Hi","version":1},{"text":" card 4111-1111-1111-1111","type":"text","version":1}`,
+	)
+	if !root.Equal(removed) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected removed document; got %s", rootB)
+	}
+
+	err := root.Valid()
+	if err != nil {
+		t.Fatal("Valid err:", err)
+	}
+
+	lexical.RegisterNodes(&nodes.MentionNode{})
+	root = document(t, `{"text":"cc ","type":"text","version":1},{"mentionName":"jane.doe@example.com","mode":"segmented","text":"@jane.doe@example.com","type":"mention","version":1}`)
+	findings = Redact(root, Options{})
+
+	expected = []Finding{
+		{Detector: "email", Field: FieldText, Start: nodes.Position{Path: nodes.Path{0, 1}, Offset: 1}, End: nodes.Position{Path: nodes.Path{0, 1}, Offset: 21}},
+		{Detector: "email", Field: FieldMentionName, Start: nodes.Position{Path: nodes.Path{0, 1}, Offset: 0}, End: nodes.Position{Path: nodes.Path{0, 1}, Offset: 20}},
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Fatalf("expected mention findings %v; got %v", expected, findings)
+	}
+
+	masked = document(t, `{"text":"cc ","type":"text","version":1},{"mentionName":"********************","mode":"segmented","text":"@********************","type":"mention","version":1}`)
+	if !root.Equal(masked) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected masked mention; got %s", rootB)
+	}
+
+	lexical.RegisterNodes(&nodes.HashtagNode{})
+	root = document(t,
+		`{"text":"cc ","type":"text","version":1},{"mentionName":"jane.doe@example.com","mode":"segmented","text":"@jane.doe@example.com","type":"mention","version":1},{"text":" ","type":"text","version":1},{"text":"#ann@example.com","type":"hashtag","version":1}`,
+		`{"children":[{"text":"ann","type":"text","version":1},{"format":1,"text":"@example.com","type":"text","version":1}],"type":"link","url":"https://example.com","version":1},{"text":" or ann@example.com","type":"text","version":1}`,
+	)
+	Redact(root, Options{Mode: Remove})
+
+	removed = document(t,
+		`{"text":"cc ","type":"text","version":1},{"mentionName":"********************","mode":"segmented","text":"@********************","type":"mention","version":1},{"text":" ","type":"text","version":1},{"text":"#***************","type":"hashtag","version":1}`,
+		`{"children":[{"text":"***","type":"text","version":1},{"format":1,"text":"************","type":"text","version":1}],"type":"link","url":"https://example.com","version":1},{"text":" or ","type":"text","version":1}`,
+	)
+	if !root.Equal(removed) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected masked text entities and link text; got %s", rootB)
+	}
+
+	err = root.Valid()
+	if err != nil {
+		t.Fatal("Valid err:", err)
+	}
+}
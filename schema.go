@@ -0,0 +1,174 @@
+package lexical
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaDraft is the JSON Schema dialect of generated schemas
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaDescriber is implemented by nodes, and by types embedded in nodes, that
+// refine the generated schema of their serialized properties
+type SchemaDescriber interface {
+	SchemaProperties() map[string]map[string]any
+}
+
+// SchemaProvider is implemented by nodes created by a factory whose schema cannot be
+// derived from their Go type, such as nodes declared at runtime
+type SchemaProvider interface {
+	JSONSchema() map[string]any
+}
+
+var (
+	nodeInterfaceType = reflect.TypeOf((*Node)(nil)).Elem()
+	nodeArrayType     = reflect.TypeOf(NodeArray{})
+	describerType     = reflect.TypeOf((*SchemaDescriber)(nil)).Elem()
+)
+
+// JSONSchema returns a JSON Schema describing a serialized node of any type in the type map
+func (tm *TypeMap) JSONSchema() map[string]any {
+	schema := map[string]any{"$ref": nodeRef("node")}
+	return tm.schemaDocument(schema)
+}
+
+// JSONSchemaFor returns a JSON Schema describing the serialized form of values of type t,
+// where nodes and node arrays may hold any type in the type map
+func (tm *TypeMap) JSONSchemaFor(t reflect.Type) map[string]any {
+	return tm.schemaDocument(typeSchema(t))
+}
+
+func (tm *TypeMap) schemaDocument(schema map[string]any) map[string]any {
+	tm.mu.RLock()
+	names := make([]string, 0, len(tm.types))
+	types := make(map[string]reflect.Type, len(tm.types))
+	factories := make(map[string]func() Node, len(tm.factories))
+	for name, t := range tm.types {
+		names = append(names, name)
+		types[name] = t
+		factories[name] = tm.factories[name]
+	}
+	tm.mu.RUnlock()
+	sort.Strings(names)
+
+	oneOf := make([]any, 0, len(names))
+	defs := map[string]any{}
+	for _, name := range names {
+		var def map[string]any
+		if provider, ok := newNode(factories[name]).(SchemaProvider); ok {
+			def = provider.JSONSchema()
+		} else {
+			def = typeSchema(types[name])
+		}
+
+		if properties, ok := def["properties"].(map[string]any); ok {
+			properties["type"] = map[string]any{"const": name}
+		}
+
+		defs["node:"+name] = def
+		oneOf = append(oneOf, map[string]any{"$ref": nodeRef("node:" + name)})
+	}
+
+	defs["node"] = map[string]any{"oneOf": oneOf}
+	schema["$schema"] = SchemaDraft
+	schema["$defs"] = defs
+	return schema
+}
+
+func newNode(factory func() Node) Node {
+	if factory == nil {
+		return nil
+	}
+
+	return factory()
+}
+
+// SchemaRef returns the reference to the definition of nodes of the named type in the schemas
+// of a type map, or to the definition of a node of any type when name is empty
+func SchemaRef(name string) string {
+	if name == "" {
+		return nodeRef("node")
+	}
+
+	return nodeRef("node:" + name)
+}
+
+// nodeRef returns a reference to a definition, escaped as a JSON pointer
+func nodeRef(name string) string {
+	name = strings.ReplaceAll(name, "~", "~0")
+	name = strings.ReplaceAll(name, "/", "~1")
+	return "#/$defs/" + name
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	switch {
+	case t == nodeArrayType:
+		return map[string]any{"type": "array", "items": map[string]any{"$ref": nodeRef("node")}}
+	case t == nodeInterfaceType:
+		return map[string]any{"$ref": nodeRef("node")}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"anyOf": []any{typeSchema(t.Elem()), map[string]any{"type": "null"}}}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		var required []string
+		structProperties(t, properties, &required)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	return map[string]any{}
+}
+
+// structProperties adds the serialized properties of a struct, including embedded structs
+func structProperties(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			structProperties(field.Type, properties, required)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = typeSchema(field.Type)
+		if name == "type" {
+			*required = append(*required, name)
+		}
+	}
+
+	if reflect.PointerTo(t).Implements(describerType) {
+		describer, _ := reflect.New(t).Interface().(SchemaDescriber)
+		for name, schema := range describer.SchemaProperties() {
+			properties[name] = schema
+		}
+	}
+}
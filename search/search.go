@@ -0,0 +1,399 @@
+// Package search implements an in-memory full-text index of lexical documents.
+package search
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tylertravisty/go-lexical"
+	"github.com/tylertravisty/go-lexical/nodes"
+)
+
+// DefaultSnippetContext is the default number of characters of a snippet around its hit
+const DefaultSnippetContext = 40
+
+// SnippetMarkID is the id of the mark nodes highlighting hits in snippets
+const SnippetMarkID = "search"
+
+// Stemmer reduces a case folded term to its stem
+type Stemmer func(term string) string
+
+// Options are the options of an index
+type Options struct {
+	// Stemmer stems indexed and searched terms when not nil
+	Stemmer Stemmer
+	// SnippetContext is the number of characters of a snippet on each side of its hit;
+	// DefaultSnippetContext when zero
+	SnippetContext int
+}
+
+// Token is a word of a text
+type Token struct {
+	// Term is the case folded and stemmed word
+	Term string
+	// Start and End are the offsets of the word in the text in UTF-16 code units, as in Lexical
+	Start int
+	End   int
+}
+
+// Hit is an occurrence of a searched term in a text node
+type Hit struct {
+	Term string
+	Path nodes.Path
+	// Start and End are the offsets of the occurrence in the text of the node in UTF-16 code units
+	Start int
+	End   int
+}
+
+// Result is a document matching every term of a query
+type Result struct {
+	ID    string
+	Score float64
+	// Hits are the occurrences of the searched terms in document order
+	Hits []Hit
+	// Snippet is the HTML of the text around the first hit, with the hit highlighted
+	Snippet string
+}
+
+// Index is an inverted index of documents. It is safe for concurrent use.
+type Index struct {
+	opts     Options
+	mu       sync.RWMutex
+	docs     map[string]indexedDocument
+	postings map[string]map[string][]Hit
+}
+
+// indexedDocument is a document with the text index its snippets are taken from
+type indexedDocument struct {
+	root *nodes.RootNode
+	text *nodes.TextIndex
+}
+
+// NewIndex returns an empty index
+func NewIndex(opts Options) *Index {
+	if opts.SnippetContext == 0 {
+		opts.SnippetContext = DefaultSnippetContext
+	}
+
+	return &Index{
+		opts:     opts,
+		docs:     map[string]indexedDocument{},
+		postings: map[string]map[string][]Hit{},
+	}
+}
+
+// Tokenize splits text into words and case folds them. Words are runs of letters, marks and
+// numbers, including apostrophes between letters, except ideographs and kana, which are
+// words of their own.
+func Tokenize(text string, stemmer Stemmer) []Token {
+	var tokens []Token
+	var term strings.Builder
+	start, offset := -1, 0
+	flush := func() {
+		if start >= 0 {
+			tokens = append(tokens, newToken(term.String(), start, offset, stemmer))
+			term.Reset()
+			start = -1
+		}
+	}
+
+	for i, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			flush()
+			tokens = append(tokens, newToken(string(fold(r)), offset, offset+utf16Len(r), stemmer))
+		case isWordRune(r) || start >= 0 && isApostrophe(r) && isWordRune(nextRune(text[i+utf8.RuneLen(r):])):
+			if start < 0 {
+				start = offset
+			}
+			term.WriteRune(fold(r))
+		default:
+			flush()
+		}
+
+		offset += utf16Len(r)
+	}
+	flush()
+
+	return tokens
+}
+
+func newToken(term string, start, end int, stemmer Stemmer) Token {
+	if stemmer != nil {
+		term = stemmer(term)
+	}
+
+	return Token{Term: term, Start: start, End: end}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+func nextRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// fold returns the simple case folding of r
+func fold(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
+
+// Add indexes a copy of the document root with id, replacing the document previously indexed
+// with id
+func (ix *Index) Add(id string, root *nodes.RootNode) {
+	root = root.Clone()
+	doc := indexedDocument{root: root, text: nodes.NewTextIndex(root, nodes.Runes)}
+	hits := map[string][]Hit{}
+	nodes.Walk(root, func(node lexical.Node, path nodes.Path) bool {
+		tn, ok := nodes.TextNodeOf(node)
+		if !ok {
+			return true
+		}
+
+		for _, token := range Tokenize(tn.Text, ix.opts.Stemmer) {
+			hits[token.Term] = append(hits[token.Term], Hit{Term: token.Term, Path: path, Start: token.Start, End: token.End})
+		}
+
+		return true
+	})
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+	ix.docs[id] = doc
+	for term, termHits := range hits {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[string][]Hit{}
+		}
+		ix.postings[term][id] = termHits
+	}
+}
+
+// Remove removes the document with id from the index
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	if _, exists := ix.docs[id]; !exists {
+		return
+	}
+
+	delete(ix.docs, id)
+	for term, docs := range ix.postings {
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+}
+
+// Len returns the number of indexed documents
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.docs)
+}
+
+// Search returns the documents holding every term of query, best scored first.
+// Documents are scored by the TF-IDF of the query terms. Repeated terms count once.
+func (ix *Index) Search(query string) []Result {
+	var terms []string
+	for _, token := range Tokenize(query, ix.opts.Stemmer) {
+		if !slices.Contains(terms, token.Term) {
+			terms = append(terms, token.Term)
+		}
+	}
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var results []Result
+	for id := range ix.postings[terms[0]] {
+		result := Result{ID: id}
+		for _, term := range terms {
+			hits := ix.postings[term][id]
+			if len(hits) == 0 {
+				result.Hits = nil
+				break
+			}
+
+			idf := math.Log(1 + float64(len(ix.docs))/float64(len(ix.postings[term])))
+			result.Score += float64(len(hits)) * idf
+			result.Hits = append(result.Hits, hits...)
+		}
+
+		if len(result.Hits) == 0 {
+			continue
+		}
+
+		sort.Slice(result.Hits, func(i, j int) bool {
+			a, b := result.Hits[i], result.Hits[j]
+			if c := a.Path.Compare(b.Path); c != 0 {
+				return c < 0
+			}
+
+			return a.Start < b.Start
+		})
+		result.Snippet = ix.snippet(ix.docs[id], result.Hits[0])
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].ID < results[j].ID
+	})
+
+	return results
+}
+
+// snippet returns the HTML of the text around hit of its top level block, or of the document
+// for a hit directly under the root, with hit highlighted by a mark node
+func (ix *Index) snippet(doc indexedDocument, hit Hit) string {
+	root, index := doc.root, doc.text
+	node, err := root.NodeAt(hit.Path)
+	if err != nil {
+		return ""
+	}
+
+	tn, ok := nodes.TextNodeOf(node)
+	if !ok {
+		return ""
+	}
+
+	block := hit.Path[:0]
+	if len(hit.Path) > 1 {
+		block = hit.Path[:1]
+	}
+
+	blockNode, err := root.NodeAt(block)
+	if err != nil {
+		return ""
+	}
+
+	parent, ok := blockNode.(nodes.Parent)
+	if !ok {
+		return ""
+	}
+
+	blockStart, err := index.Offset(nodes.Position{Path: block, Offset: 0})
+	if err != nil {
+		return ""
+	}
+
+	blockEnd, err := index.Offset(nodes.Position{Path: block, Offset: len(*parent.ChildNodes())})
+	if err != nil {
+		return ""
+	}
+
+	hitStart, err := index.Offset(nodes.Position{Path: hit.Path, Offset: runeOffset(tn.Text, hit.Start)})
+	if err != nil {
+		return ""
+	}
+
+	hitEnd, err := index.Offset(nodes.Position{Path: hit.Path, Offset: runeOffset(tn.Text, hit.End)})
+	if err != nil {
+		return ""
+	}
+
+	text := []rune(index.Text())
+
+	start := max(blockStart, hitStart-ix.opts.SnippetContext)
+	for start > blockStart && start < hitStart && !unicode.IsSpace(text[start-1]) {
+		start++
+	}
+
+	end := min(blockEnd, hitEnd+ix.opts.SnippetContext)
+	for end < blockEnd && end > hitEnd && !unicode.IsSpace(text[end]) {
+		end--
+	}
+
+	startPos, err := index.Position(start)
+	if err != nil {
+		return ""
+	}
+
+	endPos, err := index.Position(end)
+	if err != nil {
+		return ""
+	}
+
+	excerpt, err := index.Extract(startPos, endPos)
+	if err != nil {
+		return ""
+	}
+
+	// the excerpt is marked with the offsets of the hit in its text, in UTF-16 code units
+	excerptIndex := nodes.NewTextIndex(excerpt, nodes.UTF16)
+	markStart, err := excerptIndex.Position(utf16Count(text[start:hitStart]))
+	if err != nil {
+		return ""
+	}
+
+	markEnd, err := excerptIndex.Position(utf16Count(text[start:hitEnd]))
+	if err != nil {
+		return ""
+	}
+
+	err = nodes.MarkRange(excerpt, markStart, markEnd, []string{SnippetMarkID})
+	if err != nil {
+		return ""
+	}
+
+	return nodes.HTML(excerpt)
+}
+
+// runeOffset returns the offset in runes of text of offset in UTF-16 code units
+func runeOffset(text string, offset int) int {
+	n, units := 0, 0
+	for _, r := range text {
+		if units >= offset {
+			break
+		}
+
+		units += utf16Len(r)
+		n++
+	}
+
+	return n
+}
+
+func utf16Count(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		n += utf16Len(r)
+	}
+
+	return n
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tylertravisty/go-lexical"
+	"github.com/tylertravisty/go-lexical/nodes"
+)
+
+func document(t *testing.T, paragraphs ...string) *nodes.RootNode {
+	var blocks []string
+	for _, paragraph := range paragraphs {
+		blocks = append(blocks, `{"children":[`+paragraph+`],"type":"paragraph","version":1}`)
+	}
+
+	var root nodes.RootNode
+	err := json.Unmarshal([]byte(`{"root":{"children":[`+strings.Join(blocks, ",")+`],"type":"root","version":1}}`), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	return &root
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("Don't STOP, 😀 naïve Straße 東京", nil)
+
+	expected := []Token{
+		{Term: "don't", Start: 0, End: 5},
+		{Term: "stop", Start: 6, End: 10},
+		{Term: "naïve", Start: 15, End: 20},
+		{Term: "straße", Start: 21, End: 27},
+		{Term: "東", Start: 28, End: 29},
+		{Term: "京", Start: 29, End: 30},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatalf("expected tokens %v; got %v", expected, tokens)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&nodes.HashtagNode{}, &nodes.ParagraphNode{}, &nodes.TextNode{})
+
+	stemmer := func(term string) string {
+		return strings.TrimSuffix(term, "s")
+	}
+	index := NewIndex(Options{Stemmer: stemmer, SnippetContext: 12})
+
+	index.Add("a", document(t,
+		`{"text":"Notes about the quarterly ","type":"text","version":1},{"format":1,"text":"Reports","type":"text","version":1},{"text":" due in March","type":"text","version":1}`,
+	))
+	index.Add("b", document(t,
+		`{"text":"Report draft","type":"text","version":1}`,
+		`{"text":"The quarterly report is late, report again","type":"text","version":1}`,
+	))
+	index.Add("c", document(t, `{"text":"Unrelated ","type":"text","version":1},{"text":"#march","type":"hashtag","version":1}`))
+
+	results := index.Search("quarterly REPORT")
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results; got %d", len(results))
+	}
+
+	if results[0].ID != "b" || results[1].ID != "a" {
+		t.Fatalf("expected results b, a; got %s, %s", results[0].ID, results[1].ID)
+	}
+
+	hit := results[1].Hits[1]
+	if hit.Term != "report" || hit.Path.String() != "/0/1" || hit.Start != 0 || hit.End != 7 {
+		t.Fatalf("expected hit report at /0/1 [0, 7]; got %s at %v [%d, %d]", hit.Term, hit.Path, hit.Start, hit.End)
+	}
+
+	expectedSnippet := `<p>about the <mark>quarterly</mark> <strong>Reports</strong> due</p>`
+	if results[1].Snippet != expectedSnippet {
+		t.Fatalf("expected snippet %s; got %s", expectedSnippet, results[1].Snippet)
+	}
+
+	if results := index.Search("march"); len(results) != 2 {
+		t.Fatalf("expected 2 results including hashtag text; got %d", len(results))
+	}
+
+	index.Remove("b")
+	if results := index.Search("report"); len(results) != 1 || results[0].ID != "a" {
+		t.Fatal("expected only document a after removing b")
+	}
+
+	if index.Len() != 2 {
+		t.Fatalf("expected 2 documents; got %d", index.Len())
+	}
+
+	lexical.RegisterNodes(&nodes.HashtagNode{})
+	var root nodes.RootNode
+	err := json.Unmarshal([]byte(`{"root":{"children":[{"text":"Loose 😀 reports and ","type":"text","version":1},{"text":"#reports","type":"hashtag","version":1}],"type":"root","version":1}}`), &root)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	index.Add("d", &root)
+	results = index.Search("reports")
+	if len(results) != 2 || results[0].ID != "d" {
+		t.Fatalf("expected results d, a; got %v", results)
+	}
+
+	expectedSnippet = `Loose 😀 <mark>reports</mark> and`
+	if results[0].Snippet != expectedSnippet {
+		t.Fatalf("expected snippet %s; got %s", expectedSnippet, results[0].Snippet)
+	}
+
+	repeated := index.Search("reports REPORTS")
+	if !reflect.DeepEqual(repeated, results) {
+		t.Fatalf("expected results of repeated term %v; got %v", results, repeated)
+	}
+
+	root.Root.Children[0].(*nodes.TextNode).Text = "Changed"
+	if results := index.Search("reports"); len(results) != 2 || results[0].Snippet != expectedSnippet {
+		t.Fatal("expected index not to share nodes with added documents")
+	}
+}
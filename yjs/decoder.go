@@ -0,0 +1,243 @@
+package yjs
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// maxAnyDepth is the maximum nesting depth of the objects and arrays of any values
+const maxAnyDepth = 1000
+
+// decoder reads the lib0 encoding used by Yjs updates
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func (d *decoder) done() bool {
+	return d.pos >= len(d.data)
+}
+
+func (d *decoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("%s: unexpected end of update", pkg)
+	}
+
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) bytes(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, fmt.Errorf("%s: unexpected end of update", pkg)
+	}
+
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) varUint() (uint64, error) {
+	var n uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b, err := d.byte()
+		if err != nil {
+			return 0, err
+		}
+
+		n |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return n, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: varuint overflow", pkg)
+}
+
+// varLen reads a varuint used as a length or count
+func (d *decoder) varLen() (int, error) {
+	n, err := d.varUint()
+	if err != nil {
+		return 0, err
+	}
+
+	if n > uint64(len(d.data)) {
+		return 0, fmt.Errorf("%s: invalid length %d", pkg, n)
+	}
+
+	return int(n), nil
+}
+
+func (d *decoder) varInt() (int64, error) {
+	b, err := d.byte()
+	if err != nil {
+		return 0, err
+	}
+
+	n := int64(b & 0x3f)
+	negative := b&0x40 != 0
+	for shift := 6; b >= 0x80; shift += 7 {
+		if shift > 63 {
+			return 0, fmt.Errorf("%s: varint overflow", pkg)
+		}
+
+		b, err = d.byte()
+		if err != nil {
+			return 0, err
+		}
+
+		n |= int64(b&0x7f) << shift
+	}
+
+	if negative {
+		n = -n
+	}
+
+	return n, nil
+}
+
+func (d *decoder) varString() (string, error) {
+	n, err := d.varLen()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := d.bytes(n)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (d *decoder) varBytes() ([]byte, error) {
+	n, err := d.varLen()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.bytes(n)
+}
+
+func (d *decoder) json() (any, error) {
+	s, err := d.varString()
+	if err != nil {
+		return nil, err
+	}
+
+	if s == "undefined" {
+		return nil, nil
+	}
+
+	var v any
+	err = json.Unmarshal([]byte(s), &v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid json content: %v", pkg, err)
+	}
+
+	return v, nil
+}
+
+func (d *decoder) id() (id, error) {
+	client, err := d.varUint()
+	if err != nil {
+		return id{}, err
+	}
+
+	clock, err := d.varUint()
+	if err != nil {
+		return id{}, err
+	}
+
+	return id{client: client, clock: clock}, nil
+}
+
+// any reads a value in the lib0 any encoding
+func (d *decoder) any() (any, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxAnyDepth {
+		return nil, fmt.Errorf("%s: any value nested deeper than %d", pkg, maxAnyDepth)
+	}
+
+	t, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case 127, 126:
+		return nil, nil
+	case 125:
+		n, err := d.varInt()
+		return float64(n), err
+	case 124:
+		b, err := d.bytes(4)
+		if err != nil {
+			return nil, err
+		}
+
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 123:
+		b, err := d.bytes(8)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 122:
+		b, err := d.bytes(8)
+		if err != nil {
+			return nil, err
+		}
+
+		return float64(int64(binary.BigEndian.Uint64(b))), nil
+	case 121:
+		return false, nil
+	case 120:
+		return true, nil
+	case 119:
+		return d.varString()
+	case 118:
+		n, err := d.varLen()
+		if err != nil {
+			return nil, err
+		}
+
+		obj := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			key, err := d.varString()
+			if err != nil {
+				return nil, err
+			}
+
+			obj[key], err = d.any()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case 117:
+		n, err := d.varLen()
+		if err != nil {
+			return nil, err
+		}
+
+		arr := make([]any, n)
+		for i := range arr {
+			arr[i], err = d.any()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	case 116:
+		return d.varBytes()
+	}
+
+	return nil, fmt.Errorf("%s: unknown any type %d", pkg, t)
+}
@@ -0,0 +1,700 @@
+package yjs
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf16"
+)
+
+type id struct {
+	client uint64
+	clock  uint64
+}
+
+type contentKind int
+
+const (
+	contentDeleted contentKind = iota + 1
+	contentJSON
+	contentBinary
+	contentString
+	contentEmbed
+	contentFormat
+	contentType
+	contentAny
+	contentDoc
+)
+
+// content is the content of an item
+type content struct {
+	kind   contentKind
+	size   int
+	values []any
+	text   []uint16
+	key    string
+	value  any
+	typ    *sharedType
+}
+
+func (c *content) length() int {
+	switch c.kind {
+	case contentDeleted:
+		return c.size
+	case contentJSON, contentAny:
+		return len(c.values)
+	case contentString:
+		return len(c.text)
+	}
+
+	return 1
+}
+
+// split splits the content at offset, keeping the left part and returning the right part
+func (c *content) split(offset int) *content {
+	right := &content{kind: c.kind}
+	switch c.kind {
+	case contentDeleted:
+		right.size = c.size - offset
+		c.size = offset
+	case contentJSON, contentAny:
+		right.values = c.values[offset:]
+		c.values = c.values[:offset:offset]
+	case contentString:
+		right.text = c.text[offset:]
+		c.text = c.text[:offset:offset]
+	}
+
+	return right
+}
+
+type typeKind uint64
+
+const (
+	typeArray typeKind = iota
+	typeMap
+	typeText
+	typeXMLElement
+	typeXMLFragment
+	typeXMLHook
+	typeXMLText
+)
+
+// sharedType is a Yjs shared type: a list of items and a map of keyed items
+type sharedType struct {
+	kind    typeKind
+	name    string
+	start   *item
+	entries map[string]*item
+}
+
+// item is a Yjs struct. GC structs are items without a parent.
+type item struct {
+	id          id
+	length      int
+	origin      *id
+	rightOrigin *id
+	left        *item
+	right       *item
+	parent      *sharedType
+	parentID    *id
+	parentName  *string
+	parentSub   *string
+	content     *content
+	deleted     bool
+	gc          bool
+}
+
+type deleteRange struct {
+	client uint64
+	clock  uint64
+	length uint64
+}
+
+// Doc is a Yjs document built from applied updates
+type Doc struct {
+	clients map[uint64][]*item
+	shared  map[string]*sharedType
+	pending map[uint64][]*item
+	deletes []deleteRange
+}
+
+// NewDoc returns an empty Yjs document
+func NewDoc() *Doc {
+	return &Doc{
+		clients: map[uint64][]*item{},
+		shared:  map[string]*sharedType{},
+		pending: map[uint64][]*item{},
+	}
+}
+
+// Pending reports whether the document holds structs or deletions that wait for missing updates
+func (d *Doc) Pending() bool {
+	for _, items := range d.pending {
+		if len(items) > 0 {
+			return true
+		}
+	}
+
+	return len(d.deletes) > 0
+}
+
+// ApplyUpdate applies a Yjs v1 update to the document. Structs whose dependencies
+// are missing are kept until the updates they depend on are applied.
+func (d *Doc) ApplyUpdate(update []byte) error {
+	dec := &decoder{data: update}
+	err := d.readStructs(dec)
+	if err != nil {
+		return err
+	}
+
+	err = d.readDeleteSet(dec)
+	if err != nil {
+		return err
+	}
+
+	d.integratePending()
+	d.applyDeletes()
+
+	return nil
+}
+
+func (d *Doc) readStructs(dec *decoder) error {
+	numClients, err := dec.varLen()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < numClients; i++ {
+		numStructs, err := dec.varLen()
+		if err != nil {
+			return err
+		}
+
+		client, err := dec.varUint()
+		if err != nil {
+			return err
+		}
+
+		clock, err := dec.varUint()
+		if err != nil {
+			return err
+		}
+
+		for j := 0; j < numStructs; j++ {
+			it, err := readStruct(dec, id{client: client, clock: clock})
+			if err != nil {
+				return err
+			}
+
+			clock += uint64(it.length)
+			if it.content == nil && !it.gc {
+				continue
+			}
+
+			d.pending[client] = append(d.pending[client], it)
+		}
+	}
+
+	return nil
+}
+
+// readStruct reads a struct; skip structs are returned as items without content
+func readStruct(dec *decoder, itemID id) (*item, error) {
+	info, err := dec.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch info & 0x1f {
+	case 0, 10:
+		n, err := dec.varLen()
+		if err != nil {
+			return nil, err
+		}
+
+		return &item{id: itemID, length: n, deleted: true, gc: info&0x1f == 0}, nil
+	}
+
+	it := &item{id: itemID}
+	if info&0x80 != 0 {
+		origin, err := dec.id()
+		if err != nil {
+			return nil, err
+		}
+		it.origin = &origin
+	}
+
+	if info&0x40 != 0 {
+		rightOrigin, err := dec.id()
+		if err != nil {
+			return nil, err
+		}
+		it.rightOrigin = &rightOrigin
+	}
+
+	if info&0xc0 == 0 {
+		parentInfo, err := dec.varUint()
+		if err != nil {
+			return nil, err
+		}
+
+		if parentInfo == 1 {
+			name, err := dec.varString()
+			if err != nil {
+				return nil, err
+			}
+			it.parentName = &name
+		} else {
+			parentID, err := dec.id()
+			if err != nil {
+				return nil, err
+			}
+			it.parentID = &parentID
+		}
+
+		if info&0x20 != 0 {
+			sub, err := dec.varString()
+			if err != nil {
+				return nil, err
+			}
+			it.parentSub = &sub
+		}
+	}
+
+	it.content, err = readContent(dec, info&0x1f)
+	if err != nil {
+		return nil, err
+	}
+
+	it.length = it.content.length()
+	it.deleted = it.content.kind == contentDeleted
+	return it, nil
+}
+
+func readContent(dec *decoder, ref byte) (*content, error) {
+	c := &content{kind: contentKind(ref)}
+	var err error
+	switch c.kind {
+	case contentDeleted:
+		c.size, err = dec.varLen()
+	case contentJSON, contentAny:
+		var n int
+		n, err = dec.varLen()
+		if err != nil {
+			return nil, err
+		}
+
+		c.values = make([]any, n)
+		for i := range c.values {
+			if c.kind == contentJSON {
+				c.values[i], err = dec.json()
+			} else {
+				c.values[i], err = dec.any()
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	case contentBinary:
+		c.value, err = dec.varBytes()
+	case contentString:
+		var s string
+		s, err = dec.varString()
+		c.text = utf16.Encode([]rune(s))
+	case contentEmbed:
+		c.value, err = dec.json()
+	case contentFormat:
+		c.key, err = dec.varString()
+		if err != nil {
+			return nil, err
+		}
+
+		c.value, err = dec.json()
+	case contentType:
+		var kind uint64
+		kind, err = dec.varUint()
+		if err != nil {
+			return nil, err
+		}
+
+		c.typ = &sharedType{kind: typeKind(kind), entries: map[string]*item{}}
+		if c.typ.kind == typeXMLElement || c.typ.kind == typeXMLHook {
+			c.typ.name, err = dec.varString()
+		}
+	case contentDoc:
+		c.key, err = dec.varString()
+		if err != nil {
+			return nil, err
+		}
+
+		c.value, err = dec.any()
+	default:
+		return nil, fmt.Errorf("%s: unknown content type %d", pkg, ref)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (d *Doc) readDeleteSet(dec *decoder) error {
+	if dec.done() {
+		return nil
+	}
+
+	numClients, err := dec.varLen()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < numClients; i++ {
+		client, err := dec.varUint()
+		if err != nil {
+			return err
+		}
+
+		numDeletes, err := dec.varLen()
+		if err != nil {
+			return err
+		}
+
+		for j := 0; j < numDeletes; j++ {
+			clock, err := dec.varUint()
+			if err != nil {
+				return err
+			}
+
+			length, err := dec.varUint()
+			if err != nil {
+				return err
+			}
+
+			d.deletes = append(d.deletes, deleteRange{client: client, clock: clock, length: length})
+		}
+	}
+
+	return nil
+}
+
+// state returns the next expected clock of the client
+func (d *Doc) state(client uint64) uint64 {
+	items := d.clients[client]
+	if len(items) == 0 {
+		return 0
+	}
+
+	last := items[len(items)-1]
+	return last.id.clock + uint64(last.length)
+}
+
+func (d *Doc) has(i *id) bool {
+	return i == nil || i.clock < d.state(i.client)
+}
+
+// integratePending integrates pending structs until no struct has its dependencies
+func (d *Doc) integratePending() {
+	for progress := true; progress; {
+		progress = false
+		for client, items := range d.pending {
+			sort.SliceStable(items, func(i, j int) bool {
+				return items[i].id.clock < items[j].id.clock
+			})
+
+			for len(items) > 0 {
+				it := items[0]
+				state := d.state(client)
+				end := it.id.clock + uint64(it.length)
+				if end <= state {
+					items = items[1:]
+					continue
+				}
+
+				if it.id.clock > state {
+					break
+				}
+
+				if it.id.clock < state {
+					offset := int(state - it.id.clock)
+					it.origin = &id{client: client, clock: state - 1}
+					it.id.clock = state
+					if it.content != nil {
+						it.content = it.content.split(offset)
+					}
+					it.length -= offset
+				}
+
+				if !d.has(it.origin) || !d.has(it.rightOrigin) || !d.has(it.parentID) {
+					break
+				}
+
+				d.integrate(it)
+				items = items[1:]
+				progress = true
+			}
+
+			d.pending[client] = items
+			if len(items) == 0 {
+				delete(d.pending, client)
+			}
+		}
+	}
+}
+
+// find returns the integrated item containing the id
+func (d *Doc) find(target id) *item {
+	items := d.clients[target.client]
+	i := sort.Search(len(items), func(i int) bool {
+		return items[i].id.clock+uint64(items[i].length) > target.clock
+	})
+
+	if i == len(items) || items[i].id.clock > target.clock {
+		return nil
+	}
+
+	return items[i]
+}
+
+// cleanStart returns the item starting at the id, splitting the item containing it
+func (d *Doc) cleanStart(target id) *item {
+	it := d.find(target)
+	if it == nil || it.id.clock == target.clock {
+		return it
+	}
+
+	return d.split(it, int(target.clock-it.id.clock))
+}
+
+// cleanEnd returns the item ending at the id, splitting the item containing it
+func (d *Doc) cleanEnd(target id) *item {
+	it := d.find(target)
+	if it == nil {
+		return nil
+	}
+
+	if end := it.id.clock + uint64(it.length) - 1; end != target.clock {
+		d.split(it, int(target.clock-it.id.clock)+1)
+	}
+
+	return it
+}
+
+// split splits the item at offset and returns the right part
+func (d *Doc) split(it *item, offset int) *item {
+	right := &item{
+		id:          id{client: it.id.client, clock: it.id.clock + uint64(offset)},
+		length:      it.length - offset,
+		origin:      &id{client: it.id.client, clock: it.id.clock + uint64(offset) - 1},
+		rightOrigin: it.rightOrigin,
+		left:        it,
+		right:       it.right,
+		parent:      it.parent,
+		parentSub:   it.parentSub,
+		deleted:     it.deleted,
+		gc:          it.gc,
+	}
+
+	if it.content != nil {
+		right.content = it.content.split(offset)
+	}
+	it.length = offset
+
+	if it.right != nil {
+		it.right.left = right
+	}
+	it.right = right
+
+	if right.parentSub != nil && right.right == nil && right.parent != nil {
+		right.parent.entries[*right.parentSub] = right
+	}
+
+	items := d.clients[it.id.client]
+	i := sort.Search(len(items), func(i int) bool {
+		return items[i].id.clock > it.id.clock
+	})
+	items = append(items, nil)
+	copy(items[i+1:], items[i:])
+	items[i] = right
+	d.clients[it.id.client] = items
+
+	return right
+}
+
+func (d *Doc) get(name string) *sharedType {
+	t, exists := d.shared[name]
+	if !exists {
+		t = &sharedType{entries: map[string]*item{}}
+		d.shared[name] = t
+	}
+
+	return t
+}
+
+func sameID(a, b *id) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return *a == *b
+}
+
+// integrate inserts the item into its parent following the Yjs (YATA) ordering rules
+func (d *Doc) integrate(it *item) {
+	if !it.gc {
+		d.resolve(it)
+	}
+
+	if it.gc || it.parent == nil {
+		it.gc = true
+		it.deleted = true
+		it.content = nil
+		d.clients[it.id.client] = append(d.clients[it.id.client], it)
+		return
+	}
+
+	parent := it.parent
+	if (it.left == nil && (it.right == nil || it.right.left != nil)) || (it.left != nil && it.left.right != it.right) {
+		left := it.left
+		var o *item
+		switch {
+		case left != nil:
+			o = left.right
+		case it.parentSub != nil:
+			o = parent.entries[*it.parentSub]
+			for o != nil && o.left != nil {
+				o = o.left
+			}
+		default:
+			o = parent.start
+		}
+
+		conflicting := map[*item]bool{}
+		beforeOrigin := map[*item]bool{}
+		for o != nil && o != it.right {
+			beforeOrigin[o] = true
+			conflicting[o] = true
+			if sameID(it.origin, o.origin) {
+				if o.id.client < it.id.client {
+					left = o
+					clear(conflicting)
+				} else if sameID(it.rightOrigin, o.rightOrigin) {
+					break
+				}
+			} else if oOrigin := d.originItem(o); oOrigin != nil && beforeOrigin[oOrigin] {
+				if !conflicting[oOrigin] {
+					left = o
+					clear(conflicting)
+				}
+			} else {
+				break
+			}
+
+			o = o.right
+		}
+
+		it.left = left
+	}
+
+	if it.left != nil {
+		it.right = it.left.right
+		it.left.right = it
+	} else {
+		var r *item
+		if it.parentSub != nil {
+			r = parent.entries[*it.parentSub]
+			for r != nil && r.left != nil {
+				r = r.left
+			}
+		} else {
+			r = parent.start
+			parent.start = it
+		}
+		it.right = r
+	}
+
+	if it.right != nil {
+		it.right.left = it
+	} else if it.parentSub != nil {
+		parent.entries[*it.parentSub] = it
+		if it.left != nil {
+			it.left.deleted = true
+		}
+	}
+
+	if it.parentSub != nil && it.right != nil {
+		it.deleted = true
+	}
+
+	d.clients[it.id.client] = append(d.clients[it.id.client], it)
+}
+
+func (d *Doc) originItem(o *item) *item {
+	if o.origin == nil {
+		return nil
+	}
+
+	return d.find(*o.origin)
+}
+
+// resolve sets the left and right neighbours and the parent of an item before integration
+func (d *Doc) resolve(it *item) {
+	if it.origin != nil {
+		it.left = d.cleanEnd(*it.origin)
+	}
+
+	if it.rightOrigin != nil {
+		it.right = d.cleanStart(*it.rightOrigin)
+	}
+
+	if (it.left != nil && it.left.gc) || (it.right != nil && it.right.gc) {
+		it.parent = nil
+		return
+	}
+
+	switch {
+	case it.parentName != nil:
+		it.parent = d.get(*it.parentName)
+	case it.parentID != nil:
+		parent := d.find(*it.parentID)
+		if parent != nil && parent.content != nil && parent.content.kind == contentType {
+			it.parent = parent.content.typ
+		}
+	case it.left != nil:
+		it.parent = it.left.parent
+		it.parentSub = it.left.parentSub
+	case it.right != nil:
+		it.parent = it.right.parent
+		it.parentSub = it.right.parentSub
+	}
+}
+
+// applyDeletes marks deleted items, keeping ranges of missing items pending
+func (d *Doc) applyDeletes() {
+	var pending []deleteRange
+	for _, dr := range d.deletes {
+		end := dr.clock + dr.length
+		state := d.state(dr.client)
+		if state < end {
+			pending = append(pending, deleteRange{client: dr.client, clock: max(dr.clock, state), length: end - max(dr.clock, state)})
+			end = state
+		}
+
+		for clock := dr.clock; clock < end; {
+			it := d.cleanStart(id{client: dr.client, clock: clock})
+			if it == nil {
+				break
+			}
+
+			if it.id.clock+uint64(it.length) > end {
+				d.split(it, int(end-it.id.clock))
+			}
+
+			it.deleted = true
+			clock = it.id.clock + uint64(it.length)
+		}
+	}
+
+	d.deletes = pending
+}
@@ -0,0 +1,200 @@
+// Package yjs decodes documents stored by the @lexical/yjs collaboration binding.
+//
+// The binding keeps a Lexical document in a Yjs XmlText named "root". Element
+// nodes are XmlText types whose attributes hold the node properties, text nodes
+// are Y.Map properties followed by their text in the parent XmlText, and
+// decorator nodes are XmlElement types.
+package yjs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tylertravisty/go-lexical"
+	"github.com/tylertravisty/go-lexical/nodes"
+)
+
+const (
+	pkg = "yjs"
+
+	// RootName is the name of the shared type holding the lexical document
+	RootName = "root"
+)
+
+// elementFormats maps the numeric element formats stored by lexical to their serialized names
+var elementFormats = []string{"", "left", "center", "right", "justify", "start", "end"}
+
+// textModes maps the numeric text modes stored by lexical to their serialized names
+var textModes = []string{"normal", "token", "segmented"}
+
+// excludedProperties are node properties that are never serialized
+var excludedProperties = map[string]bool{
+	"key":        true,
+	"parent":     true,
+	"next":       true,
+	"prev":       true,
+	"first":      true,
+	"last":       true,
+	"size":       true,
+	"cachedText": true,
+}
+
+// Decode decodes Yjs v1 updates into a lexical document using the registered node types.
+// Updates exceeding lexical.DefaultLimits are rejected with a *lexical.LimitError.
+func Decode(updates ...[]byte) (*nodes.RootNode, error) {
+	size := 0
+	for _, update := range updates {
+		size += len(update)
+	}
+
+	if max := lexical.DefaultLimits.MaxBytes; max > 0 && size > max {
+		return nil, &lexical.LimitError{Limit: "MaxBytes", Max: max, Offset: max}
+	}
+
+	doc := NewDoc()
+	for _, update := range updates {
+		err := doc.ApplyUpdate(update)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if doc.Pending() {
+		return nil, fmt.Errorf("%s: updates have missing dependencies", pkg)
+	}
+
+	return doc.Root()
+}
+
+// Root materializes the lexical document held by the document using the registered node types.
+// Documents exceeding lexical.DefaultLimits are rejected with a *lexical.LimitError.
+func (d *Doc) Root() (*nodes.RootNode, error) {
+	root, err := elementData(d.get(RootName), 1)
+	if err != nil {
+		return nil, err
+	}
+	root["type"] = "root"
+
+	rootB, err := json.Marshal(map[string]any{"root": root})
+	if err != nil {
+		return nil, err
+	}
+
+	var rn nodes.RootNode
+	err = json.Unmarshal(rootB, &rn)
+	var limitErr *lexical.LimitError
+	if errors.As(err, &limitErr) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: error unmarshaling document: %v", pkg, err)
+	}
+
+	return &rn, nil
+}
+
+// elementData returns the serialized form of an element node held by an XmlText, whose
+// children are at depth
+func elementData(t *sharedType, depth int) (map[string]any, error) {
+	if max := lexical.DefaultLimits.MaxDepth; max > 0 && depth > max {
+		return nil, &lexical.LimitError{Limit: "MaxDepth", Max: max}
+	}
+
+	data := properties(t, true)
+	children := []any{}
+
+	var text map[string]any
+	var content strings.Builder
+	flush := func() {
+		if text != nil {
+			text["text"] = content.String()
+		}
+
+		text = nil
+		content.Reset()
+	}
+
+	for it := t.start; it != nil; it = it.right {
+		if it.deleted {
+			continue
+		}
+
+		switch it.content.kind {
+		case contentString:
+			if text == nil {
+				text = map[string]any{"type": "text", "version": 1}
+				children = append(children, text)
+			}
+
+			content.WriteString(string(utf16.Decode(it.content.text)))
+		case contentType:
+			flush()
+			typ := it.content.typ
+			switch typ.kind {
+			case typeMap:
+				text = properties(typ, false)
+				children = append(children, text)
+			case typeXMLText:
+				child, err := elementData(typ, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				children = append(children, child)
+			case typeXMLElement:
+				children = append(children, properties(typ, false))
+			}
+		}
+	}
+	flush()
+
+	data["children"] = children
+	return data, nil
+}
+
+// properties returns the node properties stored in the attributes of a shared type
+func properties(t *sharedType, element bool) map[string]any {
+	data := map[string]any{"version": 1}
+	for key, it := range t.entries {
+		name, ok := strings.CutPrefix(key, "__")
+		if !ok || excludedProperties[name] || it.deleted {
+			continue
+		}
+
+		value := entryValue(it)
+		switch {
+		case name == "dir":
+			name = "direction"
+		case name == "format" && element:
+			if n, ok := value.(float64); ok && n >= 0 && int(n) < len(elementFormats) {
+				value = elementFormats[int(n)]
+			}
+		case name == "mode" && !element:
+			if n, ok := value.(float64); ok && n >= 0 && int(n) < len(textModes) {
+				value = textModes[int(n)]
+			}
+		}
+
+		data[name] = value
+	}
+
+	return data
+}
+
+func entryValue(it *item) any {
+	switch it.content.kind {
+	case contentAny, contentJSON:
+		if len(it.content.values) > 0 {
+			return it.content.values[len(it.content.values)-1]
+		}
+	case contentString:
+		return string(utf16.Decode(it.content.text))
+	case contentBinary, contentEmbed:
+		return it.content.value
+	}
+
+	return nil
+}
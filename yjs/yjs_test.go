@@ -0,0 +1,315 @@
+package yjs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tylertravisty/go-lexical"
+	"github.com/tylertravisty/go-lexical/nodes"
+)
+
+// encoder writes the lib0 encoding of Yjs v1 updates
+type encoder struct {
+	data []byte
+}
+
+func (e *encoder) varUint(n uint64) {
+	for n >= 0x80 {
+		e.data = append(e.data, byte(n)|0x80)
+		n >>= 7
+	}
+	e.data = append(e.data, byte(n))
+}
+
+func (e *encoder) varString(s string) {
+	e.varUint(uint64(len(s)))
+	e.data = append(e.data, s...)
+}
+
+func (e *encoder) id(i id) {
+	e.varUint(i.client)
+	e.varUint(i.clock)
+}
+
+type testStruct struct {
+	origin      *id
+	rightOrigin *id
+	parentName  string
+	parentID    *id
+	parentSub   string
+	text        string
+	typ         *typeKind
+	value       any
+}
+
+func (e *encoder) item(s testStruct) {
+	var info byte
+	switch {
+	case s.typ != nil:
+		info = byte(contentType)
+	case s.value != nil:
+		info = byte(contentAny)
+	default:
+		info = byte(contentString)
+	}
+
+	if s.origin != nil {
+		info |= 0x80
+	}
+
+	if s.rightOrigin != nil {
+		info |= 0x40
+	}
+
+	if s.parentSub != "" {
+		info |= 0x20
+	}
+
+	e.data = append(e.data, info)
+	if s.origin != nil {
+		e.id(*s.origin)
+	}
+
+	if s.rightOrigin != nil {
+		e.id(*s.rightOrigin)
+	}
+
+	if s.origin == nil && s.rightOrigin == nil {
+		if s.parentName != "" {
+			e.varUint(1)
+			e.varString(s.parentName)
+		} else {
+			e.varUint(0)
+			e.id(*s.parentID)
+		}
+
+		if s.parentSub != "" {
+			e.varString(s.parentSub)
+		}
+	}
+
+	switch {
+	case s.typ != nil:
+		e.varUint(uint64(*s.typ))
+	case s.value != nil:
+		e.varUint(1)
+		switch v := s.value.(type) {
+		case string:
+			e.data = append(e.data, 119)
+			e.varString(v)
+		case int:
+			e.data = append(e.data, 125)
+			e.varUint(uint64(v))
+		}
+	default:
+		e.varString(s.text)
+	}
+}
+
+func update(clients map[uint64][]testStruct, deletes []deleteRange) []byte {
+	e := &encoder{}
+	e.varUint(uint64(len(clients)))
+	for client, structs := range clients {
+		e.varUint(uint64(len(structs)))
+		e.varUint(client)
+		e.varUint(0)
+		for _, s := range structs {
+			e.item(s)
+		}
+	}
+
+	e.varUint(uint64(len(deletes)))
+	for _, dr := range deletes {
+		e.varUint(dr.client)
+		e.varUint(1)
+		e.varUint(dr.clock)
+		e.varUint(dr.length)
+	}
+
+	return e.data
+}
+
+func TestDecode(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&nodes.ParagraphNode{}, &nodes.TextNode{})
+
+	xmlText, ymap := typeXMLText, typeMap
+	paragraph := &id{client: 1, clock: 0}
+	text := &id{client: 1, clock: 5}
+	base := update(map[uint64][]testStruct{
+		1: {
+			{parentName: RootName, typ: &xmlText},
+			{parentID: paragraph, parentSub: "__type", value: "paragraph"},
+			{parentID: paragraph, parentSub: "__format", value: 2},
+			{parentID: paragraph, parentSub: "__indent", value: 0},
+			{parentID: paragraph, parentSub: "__dir", value: "ltr"},
+			{parentID: paragraph, typ: &ymap},
+			{parentID: text, parentSub: "__type", value: "text"},
+			{parentID: text, parentSub: "__format", value: 1},
+			{parentID: text, parentSub: "__mode", value: 0},
+			{parentID: text, parentSub: "__style", value: ""},
+			{parentID: text, parentSub: "__detail", value: 0},
+			{origin: text, text: "Hello"},
+			{origin: &id{client: 1, clock: 15}, text: " world"},
+		},
+	}, nil)
+
+	concurrent := update(map[uint64][]testStruct{
+		2: {{origin: &id{client: 1, clock: 13}, rightOrigin: &id{client: 1, clock: 14}, text: "X"}},
+		3: {{origin: &id{client: 1, clock: 13}, rightOrigin: &id{client: 1, clock: 14}, text: "Y"}},
+	}, []deleteRange{{client: 1, clock: 16, length: 6}})
+
+	tests := [][][]byte{
+		{base, concurrent},
+		{concurrent, base},
+	}
+
+	for _, updates := range tests {
+		root, err := Decode(updates...)
+		if err != nil {
+			t.Fatal("Decode err:", err)
+		}
+
+		if len(root.Root.Children) != 1 {
+			t.Fatalf("expected length of root children 1; got %d", len(root.Root.Children))
+		}
+
+		p, ok := root.Root.Children[0].(*nodes.ParagraphNode)
+		if !ok {
+			t.Fatal("root child is not a paragraph")
+		}
+
+		if p.Format != "center" {
+			t.Fatalf("expected paragraph format %s; got %s", "center", p.Format)
+		}
+
+		if p.Direction == nil || *p.Direction != "ltr" {
+			t.Fatal("expected paragraph direction ltr")
+		}
+
+		if len(p.Children) != 1 {
+			t.Fatalf("expected length of paragraph children 1; got %d", len(p.Children))
+		}
+
+		tn, ok := p.Children[0].(*nodes.TextNode)
+		if !ok {
+			t.Fatal("paragraph child is not a text node")
+		}
+
+		if tn.Mode != "normal" {
+			t.Fatalf("expected text mode %s; got %s", "normal", tn.Mode)
+		}
+
+		expectedText := "HelXYlo"
+		if tn.Text != expectedText {
+			t.Fatalf("expected text %s; got %s", expectedText, tn.Text)
+		}
+
+		if tn.Format != nodes.FormatBold {
+			t.Fatalf("expected text format %d; got %d", nodes.FormatBold, tn.Format)
+		}
+	}
+}
+
+// paragraphUpdate is a Yjs v1 update, as written by Y.encodeStateAsUpdate, of a document
+// holding a paragraph with the text "Hello", written byte by byte from the lib0 encoding
+// rather than by the encoder of these tests
+var paragraphUpdate = strings.Join([]string{
+	"01", "0e", "01", "00", // one client with 14 structs: client 1 from clock 0
+	"07" + "01" + "04726f6f74" + "06",                                         // 0: XmlText in the root type "root"
+	"28" + "000100" + "065f5f74797065" + "01" + "7709" + "706172616772617068", // 1: __type = "paragraph"
+	"28" + "000100" + "085f5f666f726d6174" + "01" + "7d00",                    // 2: __format = 0
+	"28" + "000100" + "085f5f696e64656e74" + "01" + "7d00",                    // 3: __indent = 0
+	"28" + "000100" + "055f5f646972" + "01" + "7703" + "6c7472",               // 4: __dir = "ltr"
+	"28" + "000100" + "0c5f5f74657874466f726d6174" + "01" + "7d00",            // 5: __textFormat = 0
+	"28" + "000100" + "0b5f5f746578745374796c65" + "01" + "7700",              // 6: __textStyle = ""
+	"07" + "000100" + "01", // 7: Map in the paragraph
+	"28" + "000107" + "065f5f74797065" + "01" + "7704" + "74657874", // 8: __type = "text"
+	"28" + "000107" + "085f5f666f726d6174" + "01" + "7d01",          // 9: __format = 1
+	"28" + "000107" + "075f5f7374796c65" + "01" + "7700",            // 10: __style = ""
+	"28" + "000107" + "065f5f6d6f6465" + "01" + "7d00",              // 11: __mode = 0
+	"28" + "000107" + "085f5f64657461696c" + "01" + "7d00",          // 12: __detail = 0
+	"84" + "0107" + "0548656c6c6f",                                  // 13: "Hello" after the map
+	"00",                                                            // empty delete set
+}, "")
+
+func TestDecodeUpdate(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&nodes.ParagraphNode{}, &nodes.TextNode{})
+
+	update, err := hex.DecodeString(paragraphUpdate)
+	if err != nil {
+		t.Fatal("hex.DecodeString err:", err)
+	}
+
+	root, err := Decode(update)
+	if err != nil {
+		t.Fatal("Decode err:", err)
+	}
+
+	expected := `{"root":{"children":[{"children":[{"detail":0,"format":1,"mode":"normal","style":"","text":"Hello","type":"text","version":1}],"direction":"ltr","format":"","indent":0,"textFormat":0,"textStyle":"","type":"paragraph","version":1}],"direction":null,"format":"","indent":0,"type":"root","version":1}}`
+	var expectedRoot nodes.RootNode
+	err = json.Unmarshal([]byte(expected), &expectedRoot)
+	if err != nil {
+		t.Fatal("json.Unmarshal err:", err)
+	}
+
+	if !root.Equal(&expectedRoot) {
+		rootB, _ := json.Marshal(root)
+		t.Fatalf("expected document %s; got %s", expected, rootB)
+	}
+}
+
+func TestDecodeReturnsError(t *testing.T) {
+	lexical.ResetNodes()
+	lexical.RegisterNodes(&nodes.ParagraphNode{}, &nodes.TextNode{})
+
+	missing := update(map[uint64][]testStruct{
+		2: {{origin: &id{client: 1, clock: 13}, text: "X"}},
+	}, nil)
+
+	tests := [][]byte{
+		{},
+		{1, 1, 1, 0, 0xff},
+		missing,
+	}
+
+	for _, test := range tests {
+		_, err := Decode(test)
+		if err == nil {
+			t.Fatal("Decode err is nil; expected non-nil err")
+		}
+	}
+
+	xmlText := typeXMLText
+	structs := []testStruct{{parentName: RootName, typ: &xmlText}}
+	for i := 0; i < lexical.DefaultLimits.MaxDepth; i++ {
+		structs = append(structs, testStruct{parentID: &id{client: 1, clock: uint64(i)}, typ: &xmlText})
+	}
+
+	limits := []struct {
+		limit   string
+		updates [][]byte
+	}{
+		{limit: "MaxBytes", updates: [][]byte{make([]byte, lexical.DefaultLimits.MaxBytes/2+1), make([]byte, lexical.DefaultLimits.MaxBytes/2)}},
+		{limit: "MaxDepth", updates: [][]byte{update(map[uint64][]testStruct{1: structs}, nil)}},
+	}
+
+	for _, test := range limits {
+		_, err := Decode(test.updates...)
+		var limitErr *lexical.LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != test.limit {
+			t.Fatalf("expected %s limit error; got %v", test.limit, err)
+		}
+	}
+
+	dec := &decoder{data: append(bytes.Repeat([]byte{117, 1}, maxAnyDepth), 127)}
+	_, err := dec.any()
+	if err == nil {
+		t.Fatal("decoder.any err is nil; expected non-nil err")
+	}
+}